@@ -2,6 +2,7 @@ package comptest
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -13,6 +14,7 @@ import (
 	"cloud.google.com/go/pubsub"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/ingridhq/comptest"
+	"github.com/ingridhq/comptest/binary"
 	cppostgres "github.com/ingridhq/comptest/db/postgres"
 	ctpubsub "github.com/ingridhq/comptest/pubsub"
 	"github.com/ingridhq/comptest/waitfor"
@@ -36,6 +38,8 @@ var cfg struct {
 type Environment struct {
 	Sender   *pubsub.Topic
 	Receiver chan *pubsub.Message
+	// Attach must be called by every test, so unexpected exit of the binary fails the test.
+	Attach func(t testing.TB)
 }
 
 var env Environment
@@ -83,19 +87,33 @@ func TestMain(t *testing.M) {
 	)
 
 	// Build, run, wait for service and run tests...
-	cleanup := c.BuildAndRun("../main.go", waitfor.HTTP(fmt.Sprintf("http://%s/readiness", cfg.MetricPort)))
-	defer cleanup()
+	// Started binary is stopped by c.Close().
+	c.BuildAndRun("../main.go", waitfor.HTTP(fmt.Sprintf("http://%s/readiness", cfg.MetricPort)))
 
 	env = Environment{
 		Sender:   sender,
 		Receiver: receiver,
+		Attach:   c.Attach,
 		// or connection to freshly ran service.
 	}
 
-	t.Run()
+	code := t.Run()
+
+	// Binary which exited before it was stopped fails the run, even when no test noticed it.
+	if err := c.Close(); err != nil {
+		log.Printf("Failed to cleanup: %v", err)
+		var exitErr *binary.ExitError
+		if errors.As(err, &exitErr) {
+			code = 1
+		}
+	}
+	cancel()
+	os.Exit(code)
 }
 
 func Test_HTTP(t *testing.T) {
+	env.Attach(t)
+
 	resp, err := http.Get(fmt.Sprintf("http://%v/", cfg.Port))
 	if err != nil {
 		t.Fatalf("could not do get request: %v", err)
@@ -116,6 +134,8 @@ func Test_HTTP(t *testing.T) {
 }
 
 func Test_PubSubRecivied(t *testing.T) {
+	env.Attach(t)
+
 	_, err := http.Get(fmt.Sprintf("http://%v/event", cfg.Port))
 	if err != nil {
 		t.Fatalf("could not do get request: %v", err)
@@ -129,23 +149,28 @@ func Test_PubSubRecivied(t *testing.T) {
 }
 
 func Test_PubSubSend(t *testing.T) {
+	env.Attach(t)
+
 	ctx := context.Background()
 	env.Sender.Publish(ctx, &pubsub.Message{
 		Data: []byte("empty message"),
 	})
 
-	time.Sleep(250 * time.Millisecond)
-	resp, err := http.Get(fmt.Sprintf("http://%v/event_count", cfg.Port))
-	if err != nil {
-		t.Fatalf("could not do get request: %v", err)
-	}
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		t.Fatalf("could not read response body: %v", err)
-	}
-
-	if string(body) != "Current count: 1" {
-		t.Fatalf("unexpected response: %v", string(body))
-	}
+	comptest.Eventually(t, ctx, func(ctx context.Context) error {
+		resp, err := http.Get(fmt.Sprintf("http://%v/event_count", cfg.Port))
+		if err != nil {
+			return fmt.Errorf("could not do get request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("could not read response body: %w", err)
+		}
+
+		if string(body) != "Current count: 1" {
+			return fmt.Errorf("unexpected response: %v", string(body))
+		}
+		return nil
+	})
 }
@@ -1,43 +1,126 @@
 package binary
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"sync"
+	"time"
+)
+
+// RunOptions configures how the binary is run.
+type RunOptions struct {
+	// Args are command-line arguments of the binary.
+	Args []string
+	// Env is appended to the environment of the test process.
+	Env []string
+	// IsolateEnv makes Env the only environment of the binary.
+	IsolateEnv bool
+	// Dir is a working directory of the binary. Empty means the current directory.
+	Dir string
+	// ShutdownSignal is sent to the binary when it is stopped. Defaults to SIGTERM.
+	ShutdownSignal os.Signal
+	// ShutdownTimeout is how long Stop waits for the binary to exit after ShutdownSignal
+	// before it is killed. Zero means the binary is killed immediately.
+	ShutdownTimeout time.Duration
+	// RequireCleanShutdown makes Stop return *ShutdownError
+	// when the binary doesn't exit with code 0 after ShutdownSignal.
+	RequireCleanShutdown bool
+	// StderrPath is a file for standard error of the binary. Empty means it is written to logs file together with standard output.
+	StderrPath string
+	// KeepLogs is number of logs files from previous runs kept with ".1", ".2"... suffixes. Zero means logs are overwritten.
+	KeepLogs int
+	// AppendLogs makes the binary append to existing logs files instead of rotating or overwriting them,
+	// e.g. when it is restarted.
+	AppendLogs bool
+	// Tee receives a copy of the output of the binary, e.g. os.Stdout.
+	Tee io.Writer
+	// TeePrefix is prepended to every line written to Tee.
+	TeePrefix string
+	// Watch receives complete lines of the output of the binary, e.g. *logs.Watcher.
+	Watch io.Writer
+	// PIDFile stores PID of the running binary. Defaults to DefaultPIDFile of the binary.
+	PIDFile string
+	// OnStale tells what to do when binary left by previously crashed run is still running.
+	OnStale StaleAction
+}
+
+func (o RunOptions) pidFilePath(pathToBinary string) string {
+	if o.PIDFile != "" {
+		return o.PIDFile
+	}
+	return DefaultPIDFile(pathToBinary)
+}
+
+// DefaultPIDFile returns path of PID file of the binary in the temporary directory, unique for the path
+// of the binary. Directory of prebuilt binary, e.g. "/usr/local/bin", may be not writable.
+func DefaultPIDFile(pathToBinary string) string {
+	if abs, err := filepath.Abs(pathToBinary); err == nil {
+		pathToBinary = abs
+	}
+	sum := sha256.Sum256([]byte(pathToBinary))
+	return filepath.Join(os.TempDir(), fmt.Sprintf("comptest-%s-%x.pid", filepath.Base(pathToBinary), sum[:6]))
+}
 
-	"github.com/mitchellh/go-ps"
+// StaleAction tells what to do with binary left running by previously crashed run.
+type StaleAction int
+
+const (
+	// StaleFail refuses to start the binary.
+	StaleFail StaleAction = iota
+	// StaleKill kills stale binary and starts a new one.
+	StaleKill
+	// StaleAdopt uses stale binary instead of starting a new one. Its output is not captured.
+	StaleAdopt
 )
 
-// RunBinary will run golang app in a background. Returns clean function
-func RunBinary(pathToBinary string, pathToLogs string) (func(), error) {
-	if err := checkIfBinaryAlreadyRunning(pathToBinary); err != nil {
-		return nil, fmt.Errorf("binary already running: %w", err)
+// ShutdownError is returned when the binary didn't exit cleanly on shutdown.
+type ShutdownError struct {
+	// ExitCode is -1 when the binary was terminated by a signal or exit code is unknown.
+	ExitCode int
+	// Killed reports whether the binary had to be killed after ShutdownTimeout.
+	Killed bool
+	// State describes how the binary exited, e.g. "exit status 1" or "signal: killed".
+	State string
+}
+
+func (e *ShutdownError) Error() string {
+	if e.Killed {
+		return fmt.Sprintf("binary did not exit on time and was killed: %s", e.State)
 	}
+	return fmt.Sprintf("binary did not shutdown cleanly: %s", e.State)
+}
 
-	cmd := exec.Command(pathToBinary)
-	outfile, err := os.Create(pathToLogs)
+// RunBinary will run golang app in a background. Returns clean function.
+// Errors of the clean function are logged, use RunBinaryWithOptions to handle them.
+func RunBinary(pathToBinary string, pathToLogs string) (func(), error) {
+	stop, err := RunBinaryWithOptions(pathToBinary, pathToLogs, RunOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("couldn't create file: %w", err)
-	}
-	cmd.Stdout, cmd.Stderr = outfile, outfile
-	if err = cmd.Start(); err != nil {
-		return nil, fmt.Errorf("couldn't start grpc server: %w", err)
+		return nil, err
 	}
 
-	// closed indicates if "we" called closed function. Concurrent access is synchronized using closedMtx.
-	closed := false
-	closedMtx := &sync.Mutex{}
+	return func() {
+		if err := stop(); err != nil {
+			log.Printf("Failed to stop sut process: %v", err)
+		}
+	}, nil
+}
+
+// RunBinaryWithOptions works like RunBinary, but allows to configure how the binary is run.
+// The binary is started in its own process group, so clean function stops its children too.
+func RunBinaryWithOptions(pathToBinary string, pathToLogs string, opts RunOptions) (func() error, error) {
+	p, err := Start(pathToBinary, pathToLogs, opts)
+	if err != nil {
+		return nil, err
+	}
 
 	go func() {
-		// Wait for the command to exit. If it is "us" who closed (killed) the command, do nothing.
 		// If the command exited spontaneously, there is no point to continue the tests.
-		err := cmd.Wait()
-		closedMtx.Lock()
-		defer closedMtx.Unlock()
-		if !closed {
+		<-p.Exited()
+		if err := p.Err(); err != nil {
 			// Print logs error to stdout, to make debugging on CircleCI easier.
 			bytes, readErr := os.ReadFile(pathToLogs)
 			if readErr != nil {
@@ -48,43 +131,28 @@ func RunBinary(pathToBinary string, pathToLogs string) (func(), error) {
 
 			// At this point we don't have access to *testing.T.
 			// Therefore the only thing we can do is panic using log.Fatal.
-			log.Fatalf("child process %q exited before it was closed by tests: %v\n", pathToBinary, err)
+			// Use Start to handle the exit without stopping the tests.
+			log.Fatalf("%v\n", err)
 		}
 	}()
 
-	return func() {
-		closedMtx.Lock()
-		defer closedMtx.Unlock()
-
-		if err := cmd.Process.Kill(); err != nil {
-			log.Printf("Failed to kill sut process: %v", err)
-		}
-		if err := outfile.Close(); err != nil {
-			log.Printf("Failed to close logs file: %v", err)
-		}
-		closed = true
-	}, nil
-}
-
-// BuildBinary will build golang application.
-func BuildBinary(pathToGoMain, pathToBinary string) error {
-	cmd := exec.Command("go", "build", "-o", pathToBinary, pathToGoMain)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("couldn't build go app: %w", err)
-	}
-	return nil
+	return p.Stop, nil
 }
 
-func checkIfBinaryAlreadyRunning(pathToBinary string) error {
-	binaryName := filepath.Base(pathToBinary)
-	processes, err := ps.Processes()
+// CoverageProfile converts coverage counters written to coverDir by binary built with "-cover" flag
+// into coverprofile file. Requires Go 1.20+.
+func CoverageProfile(coverDir, profilePath string) error {
+	entries, err := os.ReadDir(coverDir)
 	if err != nil {
-		return fmt.Errorf("couldn't get list of processes: %v", err)
+		return fmt.Errorf("couldn't read coverage directory: %w", err)
 	}
-	for _, p := range processes {
-		if p.Executable() == binaryName {
-			return fmt.Errorf("binary %v is already running at PID %d", p.Executable(), p.Pid())
-		}
+	if len(entries) == 0 {
+		return fmt.Errorf("no coverage data in %q, make sure binary exits gracefully on shutdown signal", coverDir)
+	}
+
+	cmd := exec.Command("go", "tool", "covdata", "textfmt", "-i="+coverDir, "-o="+profilePath)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("couldn't convert coverage data: %w: %s", err, out)
 	}
 	return nil
 }
@@ -0,0 +1,195 @@
+package binary
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// BuildOptions configures how golang application is built.
+type BuildOptions struct {
+	// Tags are passed as "-tags" flag.
+	Tags []string
+	// LDFlags are passed as "-ldflags" flag.
+	LDFlags string
+	// Race enables race detector.
+	Race bool
+	// TrimPath removes file system paths from the binary.
+	TrimPath bool
+	// Flags are additional flags passed to "go build" command.
+	Flags []string
+	// Env is appended to the environment of "go build" command, e.g. "CGO_ENABLED=0".
+	Env []string
+	// Dir is a directory "go build" is run in. Empty means the current directory.
+	Dir string
+	// Cache skips the build when the binary exists and sources, flags and environment
+	// didn't change since it was built. Hash of the build is stored next to the binary.
+	Cache bool
+}
+
+func (o BuildOptions) args(pathToGoMain, pathToBinary string) []string {
+	args := []string{"build", "-o", pathToBinary}
+	if len(o.Tags) > 0 {
+		args = append(args, "-tags", strings.Join(o.Tags, ","))
+	}
+	if o.LDFlags != "" {
+		args = append(args, "-ldflags", o.LDFlags)
+	}
+	if o.Race {
+		args = append(args, "-race")
+	}
+	if o.TrimPath {
+		args = append(args, "-trimpath")
+	}
+	args = append(args, o.Flags...)
+	return append(args, pathToGoMain)
+}
+
+// BuildBinary will build golang application. Flags are passed to "go build" command.
+func BuildBinary(pathToGoMain, pathToBinary string, flags ...string) error {
+	return BuildBinaryWithOptions(pathToGoMain, pathToBinary, BuildOptions{Flags: flags})
+}
+
+// BuildBinaryWithOptions works like BuildBinary, but allows to configure the build.
+// Returned error contains output of the compiler.
+func BuildBinaryWithOptions(pathToGoMain, pathToBinary string, opts BuildOptions) error {
+	return BuildBinaryContext(context.Background(), pathToGoMain, pathToBinary, opts)
+}
+
+// BuildBinaryContext works like BuildBinaryWithOptions, but the build is stopped when ctx is done.
+func BuildBinaryContext(ctx context.Context, pathToGoMain, pathToBinary string, opts BuildOptions) error {
+	// "go build" resolves relative output path against opts.Dir, while the binary and its hash
+	// are looked for relative to the current directory.
+	pathToBinary, err := filepath.Abs(pathToBinary)
+	if err != nil {
+		return fmt.Errorf("couldn't resolve path of the binary: %w", err)
+	}
+	args := opts.args(pathToGoMain, pathToBinary)
+
+	var hash string
+	if opts.Cache {
+		if hash, err = buildHash(ctx, pathToGoMain, args, opts); err != nil {
+			return fmt.Errorf("couldn't calculate hash of sources: %w", err)
+		}
+		if isBuilt(pathToBinary, hash) {
+			return nil
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(pathToBinary), 0o755); err != nil {
+		return fmt.Errorf("couldn't create directory for binary: %w", err)
+	}
+
+	// The binary is replaced, so hash of the previous build must not match it anymore,
+	// also when this build is not cached or fails.
+	if err := os.Remove(hashPath(pathToBinary)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("couldn't remove hash of the previous build: %w", err)
+	}
+
+	stderr := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = opts.Dir
+	cmd.Stderr = stderr
+	if len(opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("couldn't build go app: %w\n%s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	if opts.Cache {
+		if err := os.WriteFile(hashPath(pathToBinary), []byte(hash), 0o644); err != nil {
+			return fmt.Errorf("couldn't store hash of the build: %w", err)
+		}
+	}
+	return nil
+}
+
+func hashPath(pathToBinary string) string {
+	return pathToBinary + ".hash"
+}
+
+func isBuilt(pathToBinary, hash string) bool {
+	if _, err := os.Stat(pathToBinary); err != nil {
+		return false
+	}
+	stored, err := os.ReadFile(hashPath(pathToBinary))
+	if err != nil {
+		return false
+	}
+	return string(stored) == hash
+}
+
+// sourceFields are fields of "go list" output with source files compiled or embedded into the binary.
+var sourceFields = []string{
+	"GoFiles", "CgoFiles", "CFiles", "CXXFiles", "MFiles", "HFiles", "FFiles",
+	"SFiles", "SwigFiles", "SwigCXXFiles", "SysoFiles", "EmbedFiles",
+}
+
+// buildHash calculates hash of all non-standard source files the application depends on,
+// together with build arguments, environment and version of go.
+func buildHash(ctx context.Context, pathToGoMain string, args []string, opts BuildOptions) (string, error) {
+	format := `{{if not .Standard}}{{$dir := .Dir}}`
+	for _, field := range sourceFields {
+		format += `{{range .` + field + `}}{{$dir}}/{{.}}{{"\n"}}{{end}}`
+	}
+	format += `{{end}}`
+
+	listArgs := []string{"list", "-deps", "-f", format}
+	if len(opts.Tags) > 0 {
+		listArgs = append(listArgs, "-tags", strings.Join(opts.Tags, ","))
+	}
+	listArgs = append(listArgs, pathToGoMain)
+
+	cmd := exec.CommandContext(ctx, "go", listArgs...)
+	cmd.Dir = opts.Dir
+	if len(opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("couldn't list sources: %w\n%s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	version, err := exec.CommandContext(ctx, "go", "version").Output()
+	if err != nil {
+		return "", fmt.Errorf("couldn't get go version: %w", err)
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%q\n%q\n", version, args, opts.Env)
+	for _, file := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if file == "" {
+			continue
+		}
+		if err := hashFile(h, file); err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFile(w io.Writer, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("couldn't open source file: %w", err)
+	}
+	defer f.Close()
+
+	fmt.Fprintf(w, "%s\n", file)
+	if _, err := io.Copy(w, f); err != nil {
+		return fmt.Errorf("couldn't read source file: %w", err)
+	}
+	return nil
+}
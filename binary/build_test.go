@@ -0,0 +1,141 @@
+package binary
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeModule writes module with main package to the directory.
+func writeModule(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	files["go.mod"] = "module example.com/app\n\ngo 1.16\n"
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+const mainGo = `package main
+
+func main() { println("%s") }
+`
+
+func TestBuildCache(t *testing.T) {
+	dir := t.TempDir()
+	writeModule(t, dir, map[string]string{
+		"main.go":  strings.Replace(mainGo, "%s", "v1", 1),
+		"header.h": "#define VERSION 1\n",
+	})
+	bin := filepath.Join(dir, "bin", "app")
+	opts := BuildOptions{Dir: dir, Cache: true}
+
+	// stub makes the binary look old, so it's possible to tell whether it was rebuilt.
+	old := time.Now().Add(-time.Hour)
+	stub := func() {
+		t.Helper()
+		if err := os.Chtimes(bin, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rebuilt := func() bool {
+		t.Helper()
+		fi, err := os.Stat(bin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fi.ModTime().After(old)
+	}
+	build := func(opts BuildOptions) {
+		t.Helper()
+		if err := BuildBinaryWithOptions(".", bin, opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	build(opts)
+	if _, err := os.Stat(hashPath(bin)); err != nil {
+		t.Fatalf("hash of the build wasn't stored: %v", err)
+	}
+
+	stub()
+	build(opts)
+	if rebuilt() {
+		t.Errorf("binary was rebuilt although nothing changed")
+	}
+
+	for _, change := range []struct {
+		name string
+		do   func()
+		opts BuildOptions
+	}{
+		{name: "go source", do: func() { writeModule(t, dir, map[string]string{"main.go": strings.Replace(mainGo, "%s", "v2", 1)}) }},
+		{name: "header", do: func() { writeModule(t, dir, map[string]string{"header.h": "#define VERSION 2\n"}) }},
+		{name: "flags", opts: BuildOptions{LDFlags: "-s"}},
+		{name: "env", opts: BuildOptions{Env: []string{"CGO_ENABLED=0"}}},
+	} {
+		stub()
+		if change.do != nil {
+			change.do()
+		}
+		changed := opts
+		changed.LDFlags, changed.Env = change.opts.LDFlags, change.opts.Env
+		build(changed)
+		if !rebuilt() {
+			t.Errorf("binary wasn't rebuilt after change of %s", change.name)
+		}
+	}
+
+	// Build without cache invalidates hash of the previous build.
+	build(BuildOptions{Dir: dir})
+	if _, err := os.Stat(hashPath(bin)); !os.IsNotExist(err) {
+		t.Errorf("hash of the previous build wasn't removed: %v", err)
+	}
+}
+
+func TestBuildRelativePathWithDir(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if err := os.Mkdir("app", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeModule(t, "app", map[string]string{"main.go": strings.Replace(mainGo, "%s", "v1", 1)})
+
+	// Output path is relative to the current directory, not to Dir.
+	if err := BuildBinaryWithOptions(".", filepath.Join("out", "app"), BuildOptions{Dir: "app", Cache: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{filepath.Join(root, "out", "app"), hashPath(filepath.Join(root, "out", "app"))} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%v", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "app", "out")); !os.IsNotExist(err) {
+		t.Errorf("binary was built relative to Dir")
+	}
+}
+
+func TestBuildError(t *testing.T) {
+	dir := t.TempDir()
+	writeModule(t, dir, map[string]string{"main.go": "package main\n\nfunc main() { undefined() }\n"})
+
+	err := BuildBinaryWithOptions(".", filepath.Join(dir, "app"), BuildOptions{Dir: dir})
+	if err == nil || !strings.Contains(err.Error(), "undefined") {
+		t.Errorf("BuildBinaryWithOptions() = %v, want compiler output", err)
+	}
+}
@@ -0,0 +1,164 @@
+package binary
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Tail returns last n lines of the file. It returns nothing when n <= 0.
+func Tail(path string, n int) (string, error) {
+	if n <= 0 {
+		return "", nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("couldn't read file: %w", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// AppendLine appends the line to the file and returns size of the file after the write.
+func AppendLine(path, line string) (int64, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't open file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		return 0, fmt.Errorf("couldn't write to file: %w", err)
+	}
+	return f.Seek(0, io.SeekCurrent)
+}
+
+// Size returns size of the file.
+func Size(path string) (int64, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't stat file: %w", err)
+	}
+	return fi.Size(), nil
+}
+
+// ReadRange returns content of the file between the offsets.
+func ReadRange(path string, from, to int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("couldn't open file: %w", err)
+	}
+	defer f.Close()
+
+	if to < from {
+		return "", nil
+	}
+	buf := make([]byte, to-from)
+	n, err := f.ReadAt(buf, from)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("couldn't read file: %w", err)
+	}
+	return string(buf[:n]), nil
+}
+
+// rotate renames path to path.1, path.1 to path.2 and so on, keeping at most keep old files.
+func rotate(path string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	os.Remove(fmt.Sprintf("%s.%d", path, keep))
+	for i := keep - 1; i >= 1; i-- {
+		old := fmt.Sprintf("%s.%d", path, i)
+		if _, err := os.Stat(old); err == nil {
+			if err := os.Rename(old, fmt.Sprintf("%s.%d", path, i+1)); err != nil {
+				return fmt.Errorf("couldn't rotate logs: %w", err)
+			}
+		}
+	}
+	if err := os.Rename(path, path+".1"); err != nil {
+		return fmt.Errorf("couldn't rotate logs: %w", err)
+	}
+	return nil
+}
+
+// createLogFile rotates old logs and creates new logs file.
+// The file is opened in append mode, so lines can be appended to it with AppendLine while the binary is running.
+// With appendLogs logs of the previous run are continued instead.
+func createLogFile(path string, keep int, appendLogs bool) (*os.File, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if !appendLogs {
+		if err := rotate(path, keep); err != nil {
+			return nil, err
+		}
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0o666)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create file: %w", err)
+	}
+	return f, nil
+}
+
+// prefixWriter writes complete lines to w, each of them prefixed.
+// Errors of w are not returned, so failing copy of the output doesn't stop writing to logs files.
+type prefixWriter struct {
+	mtx    *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+	failed bool
+}
+
+func newPrefixWriter(w io.Writer, mtx *sync.Mutex, prefix string) *prefixWriter {
+	return &prefixWriter{w: w, mtx: mtx, prefix: prefix}
+}
+
+func (pw *prefixWriter) Write(p []byte) (int, error) {
+	pw.mtx.Lock()
+	defer pw.mtx.Unlock()
+
+	pw.buf = append(pw.buf, p...)
+	for {
+		i := bytes.IndexByte(pw.buf, '\n')
+		if i < 0 {
+			break
+		}
+		pw.write(pw.buf[:i+1])
+		pw.buf = pw.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes the last line which doesn't end with new line.
+func (pw *prefixWriter) Flush() {
+	pw.mtx.Lock()
+	defer pw.mtx.Unlock()
+
+	if len(pw.buf) > 0 {
+		pw.write(append(pw.buf, '\n'))
+		pw.buf = nil
+	}
+}
+
+func (pw *prefixWriter) write(line []byte) {
+	if pw.failed {
+		return
+	}
+	if _, err := fmt.Fprintf(pw.w, "%s%s", pw.prefix, line); err != nil {
+		pw.failed = true
+		log.Printf("Failed to copy output of the binary, copying stopped: %v", err)
+	}
+}
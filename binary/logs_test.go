@@ -0,0 +1,147 @@
+package binary
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	var buf bytes.Buffer
+	mtx := &sync.Mutex{}
+	stdout := newPrefixWriter(&buf, mtx, "[api] ")
+	stderr := newPrefixWriter(&buf, mtx, "[api:err] ")
+
+	fmt.Fprint(stdout, "star")
+	fmt.Fprint(stderr, "warning\n")
+	fmt.Fprint(stdout, "ted\nlistening on ")
+	fmt.Fprint(stdout, ":8080")
+	stdout.Flush()
+	stderr.Flush()
+
+	want := "[api:err] warning\n[api] started\n[api] listening on :8080\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestPrefixWriterFailure(t *testing.T) {
+	out := log.Writer()
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(out)
+
+	w := &failingWriter{}
+	pw := newPrefixWriter(w, &sync.Mutex{}, "")
+	for i := 0; i < 3; i++ {
+		// Errors must not be returned, otherwise copying to logs files would stop too.
+		if n, err := fmt.Fprint(pw, "line\n"); n != 5 || err != nil {
+			t.Fatalf("Write() = %d, %v, want 5, nil", n, err)
+		}
+	}
+	if w.calls != 1 {
+		t.Errorf("writer was called %d times after it failed, want 1", w.calls)
+	}
+}
+
+type failingWriter struct {
+	calls int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.calls++
+	return 0, errors.New("broken pipe")
+}
+
+func TestTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("1\n2\n3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{n: 2, want: "2\n3"},
+		{n: 3, want: "1\n2\n3"},
+		{n: 10, want: "1\n2\n3"},
+		{n: 0, want: ""},
+		{n: -1, want: ""},
+	}
+	for _, tt := range tests {
+		if got, err := Tail(path, tt.n); err != nil || got != tt.want {
+			t.Errorf("Tail(%d) = %q, %v, want %q", tt.n, got, err, tt.want)
+		}
+	}
+}
+
+func TestLogsFileRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	for _, run := range []string{"first", "second", "third"} {
+		f, err := createLogFile(path, 1, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintln(f, run)
+		f.Close()
+	}
+	assertFile(t, path, "third\n")
+	assertFile(t, path+".1", "second\n")
+	if _, err := os.Stat(path + ".2"); !os.IsNotExist(err) {
+		t.Errorf("more than 1 old logs file is kept: %v", err)
+	}
+
+	f, err := createLogFile(path, 1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintln(f, "restarted")
+	f.Close()
+	assertFile(t, path, "third\nrestarted\n")
+}
+
+func TestAppendLineReadRange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("before\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	from, err := AppendLine(path, "=== BEGIN ===")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintln(f, "during")
+	f.Close()
+	to, err := Size(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := ReadRange(path, from, to); err != nil || got != "during\n" {
+		t.Errorf("ReadRange() = %q, %v, want %q", got, err, "during\n")
+	}
+	if got, err := ReadRange(path, to, from); err != nil || got != "" {
+		t.Errorf("ReadRange() of reversed range = %q, %v", got, err)
+	}
+}
+
+func assertFile(t *testing.T, path, want string) {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != want {
+		t.Errorf("%s contains %q, want %q", path, b, want)
+	}
+}
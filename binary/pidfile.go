@@ -0,0 +1,87 @@
+package binary
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/go-ps"
+)
+
+// handleStale checks PID file for binary left by previously crashed run and handles it according to action.
+// Returns adopted process or nil when new binary should be started.
+func handleStale(pathToBinary, pidFile string, action StaleAction) (*Process, error) {
+	pid, err := stalePID(pathToBinary, pidFile)
+	if err != nil {
+		return nil, err
+	}
+	if pid == 0 {
+		return nil, nil
+	}
+
+	switch action {
+	case StaleKill:
+		if err := killGroup(pid, true); err != nil {
+			return nil, fmt.Errorf("couldn't kill stale binary at PID %d: %w", pid, err)
+		}
+		for deadline := time.Now().Add(5 * time.Second); processAlive(pid); {
+			if time.Now().After(deadline) {
+				return nil, fmt.Errorf("stale binary at PID %d did not exit after kill", pid)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		return nil, nil
+	case StaleAdopt:
+		return adopt(pathToBinary, pid), nil
+	default:
+		return nil, fmt.Errorf("binary already running: binary %v is already running at PID %d (see %s)", filepath.Base(pathToBinary), pid, pidFile)
+	}
+}
+
+// stalePID returns PID stored in PID file if the process is still running the binary, 0 otherwise.
+func stalePID(pathToBinary, pidFile string) (int, error) {
+	b, err := os.ReadFile(pidFile)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("couldn't read PID file: %w", err)
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil || pid <= 0 || !processAlive(pid) {
+		return 0, nil
+	}
+
+	// PID could be reused by unrelated process.
+	p, err := ps.FindProcess(pid)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't find process %d: %w", pid, err)
+	}
+	if p == nil || !sameExecutable(p.Executable(), filepath.Base(pathToBinary)) {
+		return 0, nil
+	}
+	return pid, nil
+}
+
+// sameExecutable compares executable names, taking into account that some systems truncate them.
+func sameExecutable(executable, binaryName string) bool {
+	if executable == binaryName {
+		return true
+	}
+	const truncatedLen = 15
+	return len(executable) >= truncatedLen && strings.HasPrefix(binaryName, executable)
+}
+
+func writePIDFile(pidFile string, pid int) error {
+	if err := os.MkdirAll(filepath.Dir(pidFile), 0o755); err != nil {
+		return fmt.Errorf("couldn't create directory for PID file: %w", err)
+	}
+	if err := os.WriteFile(pidFile, []byte(strconv.Itoa(pid)), 0o644); err != nil {
+		return fmt.Errorf("couldn't write PID file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+//go:build !windows
+// +build !windows
+
+package binary
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPIDFile(t *testing.T) {
+	p := startSUT(t, RunOptions{})
+
+	b, err := os.ReadFile(p.pidFile)
+	if err != nil {
+		t.Fatalf("PID file wasn't written: %v", err)
+	}
+	if string(b) != strconv.Itoa(p.PID()) {
+		t.Errorf("PID file contains %q, want %d", b, p.PID())
+	}
+
+	if err := p.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(p.pidFile); !os.IsNotExist(err) {
+		t.Errorf("PID file wasn't removed: %v", err)
+	}
+}
+
+func TestDefaultPIDFile(t *testing.T) {
+	path := DefaultPIDFile("bin/api")
+	if filepath.Dir(path) != filepath.Clean(os.TempDir()) || !strings.HasPrefix(filepath.Base(path), "comptest-api-") {
+		t.Errorf("DefaultPIDFile() = %q, want file of the binary in temporary directory", path)
+	}
+
+	abs, err := filepath.Abs("bin/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if DefaultPIDFile(abs) != path {
+		t.Errorf("relative and absolute paths of the binary have different PID files")
+	}
+	if DefaultPIDFile("other/bin/api") == path {
+		t.Errorf("binaries with the same name have the same PID file")
+	}
+}
+
+// crashedSUT starts the sut and leaves it running, as if tests crashed before they stopped it.
+func crashedSUT(t *testing.T) (*Process, RunOptions) {
+	p := startSUT(t, RunOptions{})
+	return p, RunOptions{PIDFile: p.pidFile}
+}
+
+func TestStaleFail(t *testing.T) {
+	_, opts := crashedSUT(t)
+
+	_, err := Start(sutPath, filepath.Join(t.TempDir(), "sut.log"), opts)
+	if err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Errorf("Start() = %v, want error about running binary", err)
+	}
+}
+
+func TestStaleKill(t *testing.T) {
+	stale, opts := crashedSUT(t)
+	opts.OnStale = StaleKill
+
+	p, err := Start(sutPath, filepath.Join(t.TempDir(), "sut.log"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Stop() }()
+
+	select {
+	case <-stale.Exited():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("stale binary wasn't killed")
+	}
+	if p.PID() == stale.PID() {
+		t.Errorf("new binary wasn't started")
+	}
+}
+
+func TestStaleAdopt(t *testing.T) {
+	stale, opts := crashedSUT(t)
+	opts.OnStale = StaleAdopt
+	opts.ShutdownTimeout = 5 * time.Second
+
+	p, err := Start(sutPath, filepath.Join(t.TempDir(), "sut.log"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.PID() != stale.PID() {
+		t.Errorf("Start() started PID %d, want stale binary %d", p.PID(), stale.PID())
+	}
+
+	if err := p.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	// The binary is reaped by its original Process, which reports the exit after its output is copied.
+	select {
+	case <-stale.Exited():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("adopted binary is still running after Stop")
+	}
+	if logs := readLogs(t, stale); !strings.Contains(logs, "terminated") {
+		t.Errorf("adopted binary wasn't stopped gracefully:\n%s", logs)
+	}
+}
+
+// waitKilled waits until the process doesn't exist.
+// Children are reparented to init when the sut exits, which reaps them.
+func waitKilled(t *testing.T, pid int) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); processAlive(pid); {
+		if time.Now().After(deadline) {
+			t.Fatalf("process %d is still running", pid)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStopKillsChildren(t *testing.T) {
+	// The child ignores SIGTERM, so it is left after the sut exits.
+	p := startSUT(t, RunOptions{ShutdownTimeout: 5 * time.Second, RequireCleanShutdown: true}, "-child")
+	child := childPID(t, p)
+
+	if err := p.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	waitKilled(t, child)
+}
+
+func TestStopKillsChildrenOfExitedBinary(t *testing.T) {
+	p := startSUT(t, RunOptions{}, "-child", "-exit-after", "200ms", "-exit-code", "1")
+	child := childPID(t, p)
+	<-p.Exited()
+
+	var exitErr *ExitError
+	if err := p.Stop(); !errors.As(err, &exitErr) {
+		t.Fatalf("Stop() = %v, want *ExitError", err)
+	}
+	waitKilled(t, child)
+}
@@ -0,0 +1,50 @@
+//go:build !windows
+// +build !windows
+
+package binary
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// signalGroup sends signal to all processes in the group led by pid.
+// When pid doesn't lead a group and orProcess is set, signal is sent to the process only.
+// It's meant for processes not started by this package, PID of others could be already reused.
+func signalGroup(pid int, sig os.Signal, orProcess bool) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return fmt.Errorf("unsupported signal %v", sig)
+	}
+
+	err := syscall.Kill(-pid, s)
+	if errors.Is(err, syscall.ESRCH) && orProcess {
+		err = syscall.Kill(pid, s)
+	}
+	if err != nil && !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+	return nil
+}
+
+func killGroup(pid int, orProcess bool) error {
+	return signalGroup(pid, syscall.SIGKILL, orProcess)
+}
+
+// killRemaining kills processes left in the group led by pid after the leader exited.
+// Group ID isn't reused while the group has members, so it can't hit unrelated processes.
+func killRemaining(pid int) error {
+	return killGroup(pid, false)
+}
+
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
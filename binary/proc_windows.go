@@ -0,0 +1,39 @@
+//go:build windows
+// +build windows
+
+package binary
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Process groups are not supported on Windows, signals are sent to the process only.
+// Only os.Kill can be sent, other signals return error and the process is killed by Stop instead.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func signalGroup(pid int, sig os.Signal, orProcess bool) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return nil
+	}
+	return p.Signal(sig)
+}
+
+func killGroup(pid int, orProcess bool) error {
+	return signalGroup(pid, os.Kill, orProcess)
+}
+
+// killRemaining does nothing, without process groups nothing is left after the process exited.
+func killRemaining(pid int) error {
+	return nil
+}
+
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
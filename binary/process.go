@@ -0,0 +1,395 @@
+package binary
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Process is a running binary. It is either started by Start or adopted after previously crashed run.
+type Process struct {
+	path     string
+	logsPath string
+	pidFile  string
+	opts     RunOptions
+	pid      int
+	logFiles []*os.File
+	// adopted is set for process left by previously crashed run.
+	adopted bool
+
+	// exited is closed when the process exits.
+	exited chan struct{}
+
+	mtx sync.Mutex
+	// state is nil for adopted process.
+	state    *os.ProcessState
+	stopping bool
+	err      error
+
+	stopOnce sync.Once
+	stopErr  error
+}
+
+// ExitError is reported when the binary exited before it was stopped.
+type ExitError struct {
+	Path string
+	// ExitCode is -1 when the binary was terminated by a signal or exit code is unknown.
+	ExitCode int
+	// State describes how the binary exited, e.g. "exit status 1" or "signal: killed".
+	State string
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("child process %q exited before it was closed by tests: %s", e.Path, e.State)
+}
+
+// Start runs golang app in a background. Output of the app is written to pathToLogs.
+// The binary is started in its own process group, so Stop stops its children too.
+func Start(pathToBinary string, pathToLogs string, opts RunOptions) (*Process, error) {
+	pidFile := opts.pidFilePath(pathToBinary)
+
+	p, err := handleStale(pathToBinary, pidFile, opts.OnStale)
+	if err != nil {
+		return nil, err
+	}
+
+	if p == nil {
+		if p, err = start(pathToBinary, pathToLogs, opts); err != nil {
+			return nil, err
+		}
+	}
+	p.logsPath = pathToLogs
+	p.pidFile = pidFile
+	p.opts = opts
+
+	if err := writePIDFile(pidFile, p.pid); err != nil {
+		// The binary is stopped on a best-effort basis, the PID file error is what the caller needs.
+		_ = killGroup(p.pid, p.adopted)
+		_ = p.closeLogs()
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func newProcess(pathToBinary string, pid int) *Process {
+	return &Process{path: pathToBinary, pid: pid, exited: make(chan struct{})}
+}
+
+func start(pathToBinary, pathToLogs string, opts RunOptions) (*Process, error) {
+	cmd := exec.Command(pathToBinary, opts.Args...)
+	cmd.Dir = opts.Dir
+	switch {
+	case opts.IsolateEnv:
+		cmd.Env = append([]string{}, opts.Env...)
+	case len(opts.Env) > 0:
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
+	setProcessGroup(cmd)
+
+	logFiles, flush, err := setOutput(cmd, pathToLogs, opts)
+	if err != nil {
+		return nil, err
+	}
+	closeLogs := func() {
+		for _, f := range logFiles {
+			f.Close()
+		}
+	}
+
+	started, copied, err := pipeOutput(cmd)
+	if err != nil {
+		closeLogs()
+		return nil, err
+	}
+
+	err = cmd.Start()
+	started()
+	if err != nil {
+		closeLogs()
+		return nil, fmt.Errorf("couldn't start grpc server: %w", err)
+	}
+
+	p := newProcess(pathToBinary, cmd.Process.Pid)
+	p.logFiles = logFiles
+	go func() {
+		// Exit status is taken from ProcessState, the error only repeats it.
+		_ = cmd.Wait()
+		// Children of the binary could keep its output open, they don't delay the exit.
+		select {
+		case <-copied:
+		case <-time.After(outputDelay):
+		}
+		flush()
+		p.setExited(cmd.ProcessState)
+	}()
+	return p, nil
+}
+
+// outputDelay is how long output of exited binary is copied before the exit is reported.
+const outputDelay = 100 * time.Millisecond
+
+// pipeOutput replaces writers of the command output which are not files with pipes copied by this package.
+// Pipes created by exec.Cmd make Wait wait until all processes holding them exit, including children of the binary.
+// Returned started closes write ends of the pipes after the command started, copied is closed when all output is copied.
+func pipeOutput(cmd *exec.Cmd) (started func(), copied <-chan struct{}, err error) {
+	var writers []*os.File
+	var wg sync.WaitGroup
+	closeWriters := func() {
+		for _, w := range writers {
+			w.Close()
+		}
+	}
+
+	for _, out := range []*io.Writer{&cmd.Stdout, &cmd.Stderr} {
+		if _, ok := (*out).(*os.File); ok || *out == nil {
+			continue
+		}
+		r, w, err := os.Pipe()
+		if err != nil {
+			closeWriters()
+			return nil, nil, fmt.Errorf("couldn't create pipe for output: %w", err)
+		}
+		writers = append(writers, w)
+
+		wg.Add(1)
+		go func(dst io.Writer) {
+			defer wg.Done()
+			defer r.Close()
+			// Errors of logs files stop the copy, nothing else can be done with them.
+			_, _ = io.Copy(dst, r)
+		}(*out)
+		*out = w
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return closeWriters, done, nil
+}
+
+// setOutput creates logs files and redirects output of the command to them, RunOptions.Tee and RunOptions.Watch.
+// Returned flush writes last lines without new line to RunOptions.Tee and RunOptions.Watch after the command exits.
+func setOutput(cmd *exec.Cmd, pathToLogs string, opts RunOptions) ([]*os.File, func(), error) {
+	stdout, err := createLogFile(pathToLogs, opts.KeepLogs, opts.AppendLogs)
+	if err != nil {
+		return nil, nil, err
+	}
+	stderr := stdout
+	if opts.StderrPath != "" {
+		if stderr, err = createLogFile(opts.StderrPath, opts.KeepLogs, opts.AppendLogs); err != nil {
+			stdout.Close()
+			return nil, nil, err
+		}
+	}
+
+	var writers []*prefixWriter
+	stdoutW, stderrW := []io.Writer{stdout}, []io.Writer{stderr}
+	// Lines are buffered per stream, so lines of stdout and stderr are not mixed.
+	copyTo := func(w io.Writer, prefix string) {
+		mtx := &sync.Mutex{}
+		outW, errW := newPrefixWriter(w, mtx, prefix), newPrefixWriter(w, mtx, prefix)
+		writers = append(writers, outW, errW)
+		stdoutW, stderrW = append(stdoutW, outW), append(stderrW, errW)
+	}
+	if opts.Tee != nil {
+		copyTo(opts.Tee, opts.TeePrefix)
+	}
+	if opts.Watch != nil {
+		copyTo(opts.Watch, "")
+	}
+	cmd.Stdout, cmd.Stderr = io.MultiWriter(stdoutW...), io.MultiWriter(stderrW...)
+
+	flush := func() {
+		for _, w := range writers {
+			w.Flush()
+		}
+	}
+
+	if stderr != stdout {
+		return []*os.File{stdout, stderr}, flush, nil
+	}
+	return []*os.File{stdout}, flush, nil
+}
+
+func adopt(pathToBinary string, pid int) *Process {
+	p := newProcess(pathToBinary, pid)
+	p.adopted = true
+	go func() {
+		for processAlive(pid) {
+			time.Sleep(100 * time.Millisecond)
+		}
+		p.setExited(nil)
+	}()
+	return p
+}
+
+func (p *Process) setExited(state *os.ProcessState) {
+	p.mtx.Lock()
+	p.state = state
+	if !p.stopping {
+		p.err = &ExitError{Path: p.path, ExitCode: exitCode(state), State: stateString(state)}
+	}
+	p.mtx.Unlock()
+
+	close(p.exited)
+}
+
+// PID returns process ID of the binary.
+func (p *Process) PID() int {
+	return p.pid
+}
+
+// LogsPath returns path of the file with output of the binary.
+func (p *Process) LogsPath() string {
+	return p.logsPath
+}
+
+// StderrPath returns path of the file with standard error of the binary.
+// It is the same as LogsPath unless RunOptions.StderrPath is set.
+func (p *Process) StderrPath() string {
+	if p.opts.StderrPath != "" {
+		return p.opts.StderrPath
+	}
+	return p.logsPath
+}
+
+// Exited returns channel which is closed when the binary exits.
+func (p *Process) Exited() <-chan struct{} {
+	return p.exited
+}
+
+// Err returns *ExitError when the binary exited before it was stopped, nil otherwise.
+func (p *Process) Err() error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.err
+}
+
+// Signal sends sig to the binary. Unlike Stop, it doesn't wait for the binary to exit.
+func (p *Process) Signal(sig os.Signal) error {
+	select {
+	case <-p.exited:
+		return fmt.Errorf("binary %q has already exited", p.path)
+	default:
+	}
+
+	proc, err := os.FindProcess(p.pid)
+	if err != nil {
+		return fmt.Errorf("couldn't find process of binary %q: %w", p.path, err)
+	}
+	if err := proc.Signal(sig); err != nil {
+		return fmt.Errorf("failed to send %v to binary %q: %w", sig, p.path, err)
+	}
+	return nil
+}
+
+// Stop stops the binary: sends shutdown signal to its process group and kills it
+// if it doesn't exit on time. Returns Err when the binary exited before, its children are killed then.
+// It is safe to call Stop multiple times.
+func (p *Process) Stop() error {
+	p.stopOnce.Do(func() {
+		p.mtx.Lock()
+		p.stopping = true
+		p.mtx.Unlock()
+
+		p.stopErr = p.Err()
+		if p.stopErr == nil {
+			p.stopErr = p.shutdown()
+		} else if err := killRemaining(p.pid); err != nil {
+			// Children of the binary could outlive it, e.g. after it crashed.
+			log.Printf("Failed to kill processes left by sut process: %v", err)
+		}
+
+		os.Remove(p.pidFile)
+		if err := p.closeLogs(); err != nil && p.stopErr == nil {
+			p.stopErr = err
+		}
+	})
+	return p.stopErr
+}
+
+func (p *Process) closeLogs() error {
+	var closeErr error
+	for _, f := range p.logFiles {
+		if err := f.Close(); err != nil && closeErr == nil {
+			closeErr = fmt.Errorf("failed to close logs file: %w", err)
+		}
+	}
+	return closeErr
+}
+
+// shutdown sends shutdown signal to the process group and kills it if the process doesn't exit on time.
+func (p *Process) shutdown() error {
+	opts := p.opts
+
+	killed := true
+	if opts.ShutdownTimeout > 0 {
+		sig := opts.ShutdownSignal
+		if sig == nil {
+			sig = syscall.SIGTERM
+		}
+		// Signals other than kill are not supported e.g. on Windows, then the process is killed right away.
+		if err := signalGroup(p.pid, sig, p.adopted); err != nil {
+			log.Printf("Failed to send %v to sut process, killing it: %v", sig, err)
+		} else {
+			select {
+			case <-p.exited:
+				killed = false
+			case <-time.After(opts.ShutdownTimeout):
+			}
+		}
+	}
+
+	select {
+	case <-p.exited:
+		// The process already exited by itself. It is reaped and its PID could be reused,
+		// so only what's left in its group is killed.
+		killed = false
+		if err := killRemaining(p.pid); err != nil {
+			log.Printf("Failed to kill processes left by sut process: %v", err)
+		}
+	default:
+		if err := killGroup(p.pid, p.adopted); err != nil {
+			return fmt.Errorf("failed to kill sut process: %w", err)
+		}
+		<-p.exited
+	}
+
+	p.mtx.Lock()
+	state := p.state
+	p.mtx.Unlock()
+
+	clean := !killed && (state == nil || state.Success())
+	log.Printf("Binary %q stopped (%s, clean shutdown: %v)", p.path, stateString(state), clean)
+
+	if opts.RequireCleanShutdown && !clean {
+		return &ShutdownError{
+			ExitCode: exitCode(state),
+			Killed:   killed,
+			State:    stateString(state),
+		}
+	}
+	return nil
+}
+
+func exitCode(state *os.ProcessState) int {
+	if state == nil {
+		return -1
+	}
+	return state.ExitCode()
+}
+
+func stateString(state *os.ProcessState) string {
+	if state == nil {
+		return "adopted process exited"
+	}
+	return state.String()
+}
@@ -0,0 +1,227 @@
+//go:build !windows
+// +build !windows
+
+package binary
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// sutPath is path of the binary built from testdata/sut.
+var sutPath string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "comptest-binary")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	sutPath = filepath.Join(dir, "sut")
+
+	code := 1
+	if out, err := exec.Command("go", "build", "-o", sutPath, "../testdata/sut").CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "couldn't build sut: %v\n%s", err, out)
+	} else {
+		code = m.Run()
+	}
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// startSUT starts the sut binary with args and waits until it is ready.
+// Logs and PID file are placed in temporary directory of the test.
+func startSUT(t *testing.T, opts RunOptions, args ...string) *Process {
+	t.Helper()
+	dir := t.TempDir()
+	opts.Args = args
+	if opts.PIDFile == "" {
+		opts.PIDFile = filepath.Join(dir, "sut.pid")
+	}
+
+	p, err := Start(sutPath, filepath.Join(dir, "sut.log"), opts)
+	if err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+	t.Cleanup(func() { _ = p.Stop() })
+
+	waitForLogs(t, p.LogsPath(), "ready")
+	return p
+}
+
+// waitForLogs waits until the logs file contains the line.
+func waitForLogs(t *testing.T, path, line string) {
+	t.Helper()
+	for deadline := time.Now().Add(10 * time.Second); ; {
+		b, _ := os.ReadFile(path)
+		for _, l := range strings.Split(string(b), "\n") {
+			if l == line {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("logs don't contain %q:\n%s", line, b)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func readLogs(t *testing.T, p *Process) string {
+	t.Helper()
+	b, err := os.ReadFile(p.LogsPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func shutdownError(t *testing.T, err error) *ShutdownError {
+	t.Helper()
+	var shutdownErr *ShutdownError
+	if !errors.As(err, &shutdownErr) {
+		t.Fatalf("Stop() = %v, want *ShutdownError", err)
+	}
+	return shutdownErr
+}
+
+func TestStopGraceful(t *testing.T) {
+	p := startSUT(t, RunOptions{ShutdownTimeout: 5 * time.Second, RequireCleanShutdown: true})
+
+	if err := p.Stop(); err != nil {
+		t.Fatalf("Stop() = %v", err)
+	}
+	if logs := readLogs(t, p); !strings.Contains(logs, "terminated") {
+		t.Errorf("binary didn't handle SIGTERM:\n%s", logs)
+	}
+	if err := p.Err(); err != nil {
+		t.Errorf("Err() = %v after Stop", err)
+	}
+}
+
+func TestStopUncleanExit(t *testing.T) {
+	p := startSUT(t, RunOptions{ShutdownTimeout: 5 * time.Second, RequireCleanShutdown: true}, "-term-code", "3")
+
+	err := shutdownError(t, p.Stop())
+	if err.Killed || err.ExitCode != 3 || err.State != "exit status 3" {
+		t.Errorf("Stop() = %+v, want exit code 3", err)
+	}
+
+	// Without RequireCleanShutdown the exit code is only logged.
+	p = startSUT(t, RunOptions{ShutdownTimeout: 5 * time.Second}, "-term-code", "3")
+	if err := p.Stop(); err != nil {
+		t.Errorf("Stop() = %v", err)
+	}
+}
+
+func TestStopKillsAfterTimeout(t *testing.T) {
+	p := startSUT(t, RunOptions{ShutdownTimeout: 200 * time.Millisecond, RequireCleanShutdown: true}, "-ignore-term")
+
+	start := time.Now()
+	err := shutdownError(t, p.Stop())
+	if !err.Killed || err.ExitCode != -1 {
+		t.Errorf("Stop() = %+v, want killed binary", err)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond || elapsed > 5*time.Second {
+		t.Errorf("Stop() took %v, want ShutdownTimeout", elapsed)
+	}
+}
+
+func TestStopZeroTimeout(t *testing.T) {
+	p := startSUT(t, RunOptions{RequireCleanShutdown: true})
+
+	err := shutdownError(t, p.Stop())
+	if !err.Killed || err.State != "signal: killed" {
+		t.Errorf("Stop() = %+v, want binary killed right away", err)
+	}
+	if logs := readLogs(t, p); strings.Contains(logs, "terminated") {
+		t.Errorf("binary received SIGTERM:\n%s", logs)
+	}
+}
+
+func TestStopShutdownSignal(t *testing.T) {
+	// The sut doesn't handle SIGINT, so it is terminated by the signal.
+	p := startSUT(t, RunOptions{ShutdownSignal: syscall.SIGINT, ShutdownTimeout: 5 * time.Second, RequireCleanShutdown: true})
+
+	err := shutdownError(t, p.Stop())
+	if err.Killed || err.State != "signal: interrupt" {
+		t.Errorf("Stop() = %+v, want binary terminated by SIGINT", err)
+	}
+}
+
+type unsupportedSignal struct{}
+
+func (unsupportedSignal) Signal()        {}
+func (unsupportedSignal) String() string { return "unsupported" }
+
+func TestStopUnsupportedSignal(t *testing.T) {
+	p := startSUT(t, RunOptions{ShutdownSignal: unsupportedSignal{}, ShutdownTimeout: 5 * time.Second, RequireCleanShutdown: true})
+
+	start := time.Now()
+	err := shutdownError(t, p.Stop())
+	if !err.Killed {
+		t.Errorf("Stop() = %+v, want binary killed", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Stop() took %v, want the binary killed without waiting for ShutdownTimeout", elapsed)
+	}
+}
+
+func TestStopTwice(t *testing.T) {
+	p := startSUT(t, RunOptions{RequireCleanShutdown: true})
+
+	first := p.Stop()
+	if first == nil {
+		t.Fatalf("Stop() succeeded, want *ShutdownError")
+	}
+	if second := p.Stop(); second != first {
+		t.Errorf("second Stop() = %v, want %v", second, first)
+	}
+	select {
+	case <-p.Exited():
+	default:
+		t.Errorf("Exited() is not closed after Stop")
+	}
+}
+
+// childPID returns PID of the child started by the sut with "-child" flag.
+func childPID(t *testing.T, p *Process) int {
+	t.Helper()
+	waitForLogs(t, p.LogsPath(), "child ready")
+	for _, line := range strings.Split(readLogs(t, p), "\n") {
+		if strings.HasPrefix(line, "child ") {
+			if pid, err := strconv.Atoi(strings.TrimPrefix(line, "child ")); err == nil {
+				return pid
+			}
+		}
+	}
+	t.Fatalf("PID of the child isn't logged")
+	return 0
+}
+
+func TestExitedWithChildHoldingOutput(t *testing.T) {
+	p := startSUT(t, RunOptions{}, "-child", "-exit-after", "200ms", "-exit-code", "1")
+	child := childPID(t, p)
+	defer func() { _ = syscall.Kill(child, syscall.SIGKILL) }()
+
+	// The child inherited output of the sut and keeps running.
+	select {
+	case <-p.Exited():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("exit of the binary wasn't reported while its child is running")
+	}
+	var exitErr *ExitError
+	if !errors.As(p.Err(), &exitErr) || exitErr.ExitCode != 1 {
+		t.Errorf("Err() = %v, want exit code 1", p.Err())
+	}
+	if logs := readLogs(t, p); !strings.Contains(logs, "exiting") {
+		t.Errorf("last output of the binary is missing:\n%s", logs)
+	}
+}
@@ -4,101 +4,303 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
+	"sync"
+	"time"
 
-	"github.com/cenkalti/backoff/v4"
 	"github.com/ingridhq/comptest/binary"
+	"github.com/ingridhq/comptest/internal/multierr"
+	"github.com/ingridhq/comptest/logs"
+	"github.com/ingridhq/comptest/waitfor"
 	"golang.org/x/sync/errgroup"
 )
 
-type checker interface {
-	Check(ctx context.Context) error
-}
+// Checker is a health check which is retried until it succeeds.
+// Combinators for checks can be found in waitfor package.
+type Checker = waitfor.Checker
 
 type CleanupFunc func()
 
 type comptest struct {
 	ctx context.Context
+	cfg config
+
+	cleanupsMtx sync.Mutex
+	cleanups    []func() error
 
-	binaryPath string
-	logsPath   string
+	coverDirsMtx sync.Mutex
+	coverDirs    map[string]string
+
+	processesMtx sync.Mutex
+	processes    []*SUT
 }
 
 // New create new comptests suite.
-func New(ctx context.Context) *comptest {
+func New(ctx context.Context, opts ...Option) *comptest {
 	return &comptest{
-		binaryPath: os.TempDir() + "/main",
-		logsPath:   "./comptest.log",
-		ctx:        ctx,
+		ctx: ctx,
+		cfg: defaultConfig().with(opts...),
 	}
 }
 
 // SetBinaryPath sets custom path where binary will be build.
 func (c *comptest) SetBinaryPath(binaryPath string) {
-	c.binaryPath = binaryPath
+	c.cfg.binaryPath = binaryPath
 }
 
 // SetLogsPath sets custom file to store logs from binary.
 func (c *comptest) SetLogsPath(logsPath string) {
-	c.logsPath = logsPath
+	c.cfg.logsPath = logsPath
+}
+
+// SetRetryPolicy sets policy used to retry checks. Single check can override it with waitfor.WithRetryPolicy.
+func (c *comptest) SetRetryPolicy(policy waitfor.RetryPolicy) {
+	c.cfg.retryPolicy = policy
+}
+
+// SetReporter sets reporter which receives progress of health checks.
+func (c *comptest) SetReporter(reporter Reporter) {
+	c.cfg.reporter = reporter
 }
 
 // HealthChecks waits for external dependencies (PubSubs, Databases, GRPC mocks) to be ready.
-func (c *comptest) HealthChecks(checks ...checker) {
-	if err := waitForAll(c.ctx, checks...); err != nil {
-		log.Fatalf("Failed to check external dependencies: %v", err)
+// On failure all registered cleanups are invoked and the process exits.
+func (c *comptest) HealthChecks(checks ...Checker) {
+	if err := c.TryHealthChecks(checks...); err != nil {
+		c.fatal(err)
 	}
 }
 
+// TryHealthChecks waits for external dependencies (PubSubs, Databases, GRPC mocks) to be ready.
+func (c *comptest) TryHealthChecks(checks ...Checker) error {
+	if err := c.waitForAll(c.ctx, c.cfg, checks...); err != nil {
+		return fmt.Errorf("failed to check external dependencies: %w", err)
+	}
+	return nil
+}
+
 // BuildAndRun builds, runs binary, waits for readiness check and runs tests.
+// Options override configuration of the suite for this binary only.
 // Returns cleanup function that needs to be invoked after tests.
-func (c *comptest) BuildAndRun(buildPath string, readiness checker) CleanupFunc {
-	if err := binary.BuildBinary(buildPath, c.binaryPath); err != nil {
-		log.Fatalf("Failed to build binary: %v", err)
+// On failure all registered cleanups are invoked and the process exits.
+func (c *comptest) BuildAndRun(buildPath string, readiness Checker, opts ...Option) CleanupFunc {
+	cleanup, err := c.TryBuildAndRun(buildPath, readiness, opts...)
+	if err != nil {
+		c.fatal(err)
 	}
+	return cleanup
+}
 
-	return c.Run(c.binaryPath, readiness)
+// TryBuildAndRun builds, runs binary and waits for readiness check.
+// Returned cleanup function is also registered in the suite and invoked by Close.
+func (c *comptest) TryBuildAndRun(buildPath string, readiness Checker, opts ...Option) (CleanupFunc, error) {
+	cfg := c.cfg.with(opts...)
+	if err := build(c.ctx, cfg, buildPath); err != nil {
+		return nil, err
+	}
+
+	return c.run(c.ctx, "", cfg, cfg.binaryPath, readiness)
 }
 
 // Runs binary, waits for readiness check and runs tests.
+// Options override configuration of the suite for this binary only.
 // Returns cleanup function that needs to be invoked after tests.
-func (c *comptest) Run(runPath string, readiness checker) CleanupFunc {
-	cleaner, err := binary.RunBinary(runPath, c.logsPath)
+// On failure all registered cleanups are invoked and the process exits.
+func (c *comptest) Run(runPath string, readiness Checker, opts ...Option) CleanupFunc {
+	cleanup, err := c.TryRun(runPath, readiness, opts...)
+	if err != nil {
+		c.fatal(err)
+	}
+	return cleanup
+}
+
+// TryRun runs binary and waits for readiness check.
+// Returned cleanup function is also registered in the suite and invoked by Close.
+func (c *comptest) TryRun(runPath string, readiness Checker, opts ...Option) (CleanupFunc, error) {
+	return c.run(c.ctx, "", c.cfg.with(opts...), runPath, readiness)
+}
+
+func build(ctx context.Context, cfg config, buildPath string) error {
+	opts := cfg.build
+	opts.Flags = append(cfg.coverageBuildFlags(), opts.Flags...)
+	if err := binary.BuildBinaryContext(ctx, buildPath, cfg.binaryPath, opts); err != nil {
+		return fmt.Errorf("failed to build binary: %w", err)
+	}
+	return nil
+}
+
+// run starts binary and waits for its readiness until ctx is done.
+func (c *comptest) run(ctx context.Context, name string, cfg config, runPath string, readiness Checker) (CleanupFunc, error) {
+	s, cleanup, err := c.start(name, cfg, runPath, readiness)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.waitReady(ctx); err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	return cleanup, nil
+}
+
+// start runs binary and registers it in the suite.
+func (c *comptest) start(name string, cfg config, runPath string, readiness Checker) (*SUT, CleanupFunc, error) {
+	env, err := cfg.environ()
 	if err != nil {
-		log.Fatalf("Failed to run binary: %v", err)
+		return nil, nil, fmt.Errorf("failed to prepare environment: %w", err)
 	}
+	args, err := cfg.arguments()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var coverDir string
+	if cfg.coverProfile != "" {
+		if coverDir, err = c.coverageDir(cfg.coverProfile); err != nil {
+			return nil, nil, err
+		}
+		env = append(env, "GOCOVERDIR="+coverDir)
+	}
+
+	watcher := logs.NewWatcher()
+	s := &SUT{
+		suite:     c,
+		name:      name,
+		path:      runPath,
+		cfg:       cfg,
+		readiness: readiness,
+		logs:      watcher,
+		opts: binary.RunOptions{
+			Args:                 args,
+			Env:                  env,
+			IsolateEnv:           cfg.isolateEnv,
+			Dir:                  cfg.workDir,
+			ShutdownSignal:       cfg.shutdownSignal,
+			ShutdownTimeout:      cfg.shutdownTimeout,
+			RequireCleanShutdown: cfg.cleanShutdown,
+			PIDFile:              cfg.pidFile,
+			OnStale:              cfg.onStale,
+			StderrPath:           cfg.stderrPath,
+			KeepLogs:             cfg.keepLogs,
+			Tee:                  cfg.tee,
+			TeePrefix:            cfg.teePrefix,
+			Watch:                watcher,
+		},
+	}
+	if err := s.start(s.opts); err != nil {
+		return nil, nil, err
+	}
+
+	c.processesMtx.Lock()
+	c.processes = append(c.processes, s)
+	c.processesMtx.Unlock()
+
+	stop := s.close
+	if coverDir != "" {
+		stop = withCoverage(stop, coverDir, cfg.coverProfile)
+	}
+	return s, c.AddCleanup(stop), nil
+}
+
+// Logs returns watcher of the output of the most recently started binary.
+// It returns nil when no binary was started.
+func (c *comptest) Logs() *logs.Watcher {
+	c.processesMtx.Lock()
+	defer c.processesMtx.Unlock()
+
+	if len(c.processes) == 0 {
+		return nil
+	}
+	return c.processes[len(c.processes)-1].logs
+}
 
-	if err := waitForAll(c.ctx, readiness); err != nil {
-		cleaner()
-		log.Fatalf("Failed to check readiness: %v", err)
+// SUT returns binary started as a service with the name, nil when there is no such service.
+// Empty name returns the most recently started binary started with Run or BuildAndRun.
+func (c *comptest) SUT(name string) *SUT {
+	c.processesMtx.Lock()
+	defer c.processesMtx.Unlock()
+
+	for i := len(c.processes) - 1; i >= 0; i-- {
+		if s := c.processes[i]; s.name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// AddCleanup registers function that will be invoked by Close.
+// Returned CleanupFunc can be used to invoke it earlier, it is run at most once.
+func (c *comptest) AddCleanup(fn func() error) CleanupFunc {
+	var (
+		once sync.Once
+		err  error
+	)
+	run := func() error {
+		once.Do(func() { err = fn() })
+		return err
 	}
 
-	return cleaner
+	c.cleanupsMtx.Lock()
+	c.cleanups = append(c.cleanups, run)
+	c.cleanupsMtx.Unlock()
+
+	return func() {
+		if err := run(); err != nil {
+			log.Printf("Cleanup failed: %v", err)
+		}
+	}
 }
 
-func waitForAll(ctx context.Context, checks ...checker) error {
+// Close invokes all registered cleanups in reverse order of registration.
+// Returns all errors that occurred during cleanup, including *binary.ExitError
+// when a binary exited before it was stopped. Use errors.As to find it among several errors.
+// TestMain should fail the run on it, the exit could happen when no test was attached.
+func (c *comptest) Close() error {
+	c.cleanupsMtx.Lock()
+	cleanups := c.cleanups
+	c.cleanups = nil
+	c.cleanupsMtx.Unlock()
+
+	var errs multierr.Errors
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		if err := cleanups[i](); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return multierr.Combine(errs)
+}
+
+// fatal cleans up everything registered in the suite and exits.
+func (c *comptest) fatal(err error) {
+	if cerr := c.Close(); cerr != nil {
+		log.Printf("Failed to cleanup: %v", cerr)
+	}
+	log.Fatal(err)
+}
+
+func (c *comptest) waitForAll(ctx context.Context, cfg config, checks ...Checker) error {
+	start := time.Now()
+	results := make([]Result, len(checks))
+
 	g, ctx := errgroup.WithContext(ctx)
-	for _, c := range checks {
-		c := c
+	for i, check := range checks {
+		i, check := i, check
+		name := fmt.Sprintf("%v", check)
+		results[i].Check = name
+
 		g.Go(func() error {
-			return backoff.Retry(func() error {
-				err := c.Check(ctx)
-
-				select {
-				case <-ctx.Done():
-					if err != nil {
-						return backoff.Permanent(fmt.Errorf("check %v failed: %w", c, err))
-					}
-					return nil
-				default:
-					if err != nil {
-						return fmt.Errorf("check %v failed: %w", c, err)
-					}
-					return nil
-				}
-			}, backoff.NewExponentialBackOff())
+			err := waitfor.WaitNotify(ctx, check, cfg.retryPolicy, func(attempt int, err error) {
+				elapsed := time.Since(start)
+				results[i].Attempts = attempt
+				results[i].Elapsed = elapsed
+				cfg.reporter.Attempt(Event{Check: name, Attempt: attempt, Err: err, Elapsed: elapsed})
+			})
+			results[i].Err = err
+			return err
 		})
 	}
 
-	return g.Wait()
+	err := g.Wait()
+	cfg.reporter.Summary(results)
+	return err
 }
@@ -0,0 +1,108 @@
+package comptest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ingridhq/comptest/waitfor"
+)
+
+// EventuallyOption configures Eventually.
+type EventuallyOption func(cfg *eventuallyConfig)
+
+type eventuallyConfig struct {
+	timeout time.Duration
+	policy  waitfor.RetryPolicy
+}
+
+// EventuallyTimeout sets how long Eventually waits for the condition, 10s by default.
+func EventuallyTimeout(timeout time.Duration) EventuallyOption {
+	return func(cfg *eventuallyConfig) {
+		cfg.timeout = timeout
+	}
+}
+
+// EventuallyRetryPolicy sets how often the condition is checked, every 50ms growing up to 1s by default.
+func EventuallyRetryPolicy(policy waitfor.RetryPolicy) EventuallyOption {
+	return func(cfg *eventuallyConfig) {
+		cfg.policy = policy
+	}
+}
+
+// Eventually checks the condition until it returns nil. When it doesn't succeed on time
+// or ctx is done, the test fails with the last error of the condition.
+// Condition can return waitfor.Permanent error to fail the test immediately.
+func Eventually(t testing.TB, ctx context.Context, fn func(ctx context.Context) error, opts ...EventuallyOption) {
+	t.Helper()
+
+	cfg := eventuallyConfig{
+		timeout: 10 * time.Second,
+		policy:  waitfor.ExponentialRetryPolicy(50*time.Millisecond, time.Second),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.timeout)
+	defer cancel()
+
+	start := time.Now()
+	attempts := 0
+	err := waitfor.WaitNotify(ctx, waitfor.Func("condition", fn), cfg.policy, func(attempt int, err error) {
+		attempts = attempt
+	})
+	if err != nil {
+		t.Fatalf("condition not met after %d attempts in %v: %v", attempts, time.Since(start).Round(time.Millisecond), err)
+	}
+}
+
+// consistentlyInterval is used by Consistently when interval is not positive.
+const consistentlyInterval = 100 * time.Millisecond
+
+// Consistently checks the condition every interval for the whole duration and fails the test
+// as soon as it returns error, e.g. to assert that no message arrives on a subscription.
+// The condition is checked at the beginning and at the end of the duration too.
+// Every check is limited by the remaining duration, but at least by the interval.
+// Interval <= 0 means 100ms.
+func Consistently(t testing.TB, ctx context.Context, duration, interval time.Duration, fn func(ctx context.Context) error) {
+	t.Helper()
+
+	if interval <= 0 {
+		interval = consistentlyInterval
+	}
+
+	start := time.Now()
+	for poll := 1; ; poll++ {
+		remaining := duration - time.Since(start)
+		timeout := remaining
+		if timeout < interval {
+			timeout = interval
+		}
+
+		pollCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := fn(pollCtx)
+		cancel()
+		if err != nil {
+			t.Fatalf("condition broke at poll %d after %v (expected to hold for %v): %v",
+				poll, time.Since(start).Round(time.Millisecond), duration, err)
+		}
+
+		remaining = duration - time.Since(start)
+		if remaining <= 0 {
+			return
+		}
+		if remaining > interval {
+			remaining = interval
+		}
+
+		timer := time.NewTimer(remaining)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			t.Fatalf("condition checked %d times in %v, but not for the whole %v: %v",
+				poll, time.Since(start).Round(time.Millisecond), duration, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package comptest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ingridhq/comptest/waitfor"
+)
+
+// fatalT records failure of a test and stops it like testing.T does.
+type fatalT struct {
+	testing.TB
+	failure string
+}
+
+func (t *fatalT) Helper() {}
+
+func (t *fatalT) Fatalf(format string, args ...interface{}) {
+	t.failure = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+// failure runs the test function and returns its failure, empty when it passed.
+func failure(fn func(t testing.TB)) string {
+	t := &fatalT{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(t)
+	}()
+	<-done
+	return t.failure
+}
+
+func TestEventually(t *testing.T) {
+	attempts := 0
+	msg := failure(func(t testing.TB) {
+		Eventually(t, context.Background(), func(ctx context.Context) error {
+			if attempts++; attempts < 3 {
+				return errors.New("no message")
+			}
+			return nil
+		}, EventuallyRetryPolicy(waitfor.ConstantRetryPolicy(time.Millisecond)))
+	})
+	if msg != "" || attempts != 3 {
+		t.Errorf("Eventually() failed with %q after %d attempts", msg, attempts)
+	}
+}
+
+func TestEventuallyTimeout(t *testing.T) {
+	start := time.Now()
+	msg := failure(func(t testing.TB) {
+		Eventually(t, context.Background(), func(ctx context.Context) error {
+			return errors.New("no message")
+		}, EventuallyTimeout(100*time.Millisecond), EventuallyRetryPolicy(waitfor.ConstantRetryPolicy(10*time.Millisecond)))
+	})
+	if !strings.HasPrefix(msg, "condition not met after ") || !strings.HasSuffix(msg, ": no message") {
+		t.Errorf("Eventually() failed with %q, want the last error of the condition", msg)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Eventually() took %v, want EventuallyTimeout", elapsed)
+	}
+}
+
+func TestEventuallyPermanent(t *testing.T) {
+	attempts := 0
+	msg := failure(func(t testing.TB) {
+		Eventually(t, context.Background(), func(ctx context.Context) error {
+			attempts++
+			return waitfor.Permanent(errors.New("order rejected"))
+		})
+	})
+	if !strings.Contains(msg, "after 1 attempts") || !strings.Contains(msg, "order rejected") || attempts != 1 {
+		t.Errorf("Eventually() failed with %q after %d attempts, want to fail immediately", msg, attempts)
+	}
+}
+
+func TestEventuallyContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	msg := failure(func(t testing.TB) {
+		Eventually(t, ctx, func(ctx context.Context) error {
+			return errors.New("no message")
+		})
+	})
+	if msg == "" {
+		t.Errorf("Eventually() passed with done context")
+	}
+}
+
+func TestConsistently(t *testing.T) {
+	polls := 0
+	start := time.Now()
+	msg := failure(func(t testing.TB) {
+		Consistently(t, context.Background(), 100*time.Millisecond, 20*time.Millisecond, func(ctx context.Context) error {
+			polls++
+			return nil
+		})
+	})
+	if msg != "" {
+		t.Fatalf("Consistently() failed with %q", msg)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Consistently() returned after %v, want the whole duration", elapsed)
+	}
+	// The condition is checked at the beginning, every interval and at the end.
+	if polls < 2 || polls > 7 {
+		t.Errorf("condition was checked %d times, want about 6", polls)
+	}
+}
+
+func TestConsistentlyBreaks(t *testing.T) {
+	polls := 0
+	msg := failure(func(t testing.TB) {
+		Consistently(t, context.Background(), time.Second, time.Millisecond, func(ctx context.Context) error {
+			if polls++; polls == 3 {
+				return errors.New("event_count is 2")
+			}
+			return nil
+		})
+	})
+	if !strings.HasPrefix(msg, "condition broke at poll 3 after ") || !strings.HasSuffix(msg, "(expected to hold for 1s): event_count is 2") {
+		t.Errorf("Consistently() failed with %q, want poll which broke the condition", msg)
+	}
+}
+
+func TestConsistentlyContextDone(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	msg := failure(func(t testing.TB) {
+		Consistently(t, ctx, time.Minute, 10*time.Millisecond, func(ctx context.Context) error {
+			return nil
+		})
+	})
+	if !strings.Contains(msg, "but not for the whole 1m0s: context deadline exceeded") {
+		t.Errorf("Consistently() failed with %q, want error of the context", msg)
+	}
+}
+
+func TestConsistentlyPollTimeout(t *testing.T) {
+	// Every poll gets at least the interval, also when the duration is over.
+	var deadlines []time.Duration
+	msg := failure(func(t testing.TB) {
+		Consistently(t, context.Background(), 0, 0, func(ctx context.Context) error {
+			deadline, _ := ctx.Deadline()
+			deadlines = append(deadlines, time.Until(deadline))
+			return nil
+		})
+	})
+	if msg != "" || len(deadlines) != 1 {
+		t.Fatalf("Consistently() failed with %q after %d polls, want single poll", msg, len(deadlines))
+	}
+	if deadlines[0] <= 0 || deadlines[0] > consistentlyInterval {
+		t.Errorf("poll timeout = %v, want default interval", deadlines[0])
+	}
+}
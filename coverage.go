@@ -0,0 +1,74 @@
+package comptest
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ingridhq/comptest/binary"
+	"github.com/ingridhq/comptest/internal/multierr"
+)
+
+// WithCoverage builds the binary with "-cover" flag (requires Go 1.20+) and writes its coverage
+// to coverprofile file when the binary is stopped. The profile has the same format as the one
+// produced by "go test -coverprofile", so both can be combined.
+// Optional packages are passed as "-coverpkg" flag.
+//
+// Coverage is written only when the binary exits gracefully on shutdown signal (see WithShutdownTimeout).
+func WithCoverage(profilePath string, packages ...string) Option {
+	return func(cfg *config) {
+		cfg.coverProfile = profilePath
+		cfg.coverPackages = packages
+	}
+}
+
+func (cfg config) coverageBuildFlags() []string {
+	if cfg.coverProfile == "" {
+		return nil
+	}
+	flags := []string{"-cover"}
+	if len(cfg.coverPackages) > 0 {
+		flags = append(flags, "-coverpkg="+strings.Join(cfg.coverPackages, ","))
+	}
+	return flags
+}
+
+// coverageDir returns directory for coverage counters of the profile.
+// The directory is shared by all runs of binaries writing to the same profile,
+// so profile contains coverage of all of them.
+func (c *comptest) coverageDir(profilePath string) (string, error) {
+	c.coverDirsMtx.Lock()
+	defer c.coverDirsMtx.Unlock()
+
+	if dir, ok := c.coverDirs[profilePath]; ok {
+		return dir, nil
+	}
+
+	dir, err := os.MkdirTemp("", "comptest-cover")
+	if err != nil {
+		return "", fmt.Errorf("failed to create coverage directory: %w", err)
+	}
+	if c.coverDirs == nil {
+		c.coverDirs = map[string]string{}
+	}
+	c.coverDirs[profilePath] = dir
+	c.AddCleanup(func() error {
+		return os.RemoveAll(dir)
+	})
+
+	return dir, nil
+}
+
+// withCoverage writes coverage profile after the binary is stopped.
+func withCoverage(stop func() error, coverDir, profilePath string) func() error {
+	return func() error {
+		var errs multierr.Errors
+		if err := stop(); err != nil {
+			errs = append(errs, err)
+		}
+		if err := binary.CoverageProfile(coverDir, profilePath); err != nil {
+			errs = append(errs, err)
+		}
+		return multierr.Combine(errs)
+	}
+}
@@ -24,7 +24,7 @@ func (c database) String() string {
 	return fmt.Sprintf("[MySQL: %s]", c.dsn)
 }
 
-// Check implements checker interface for convenient use in HealthChecks function.
+// Check implements Checker interface for convenient use in HealthChecks function.
 func (c database) Check(ctx context.Context) error {
 	db, err := sqlx.ConnectContext(ctx, schema, c.dsn)
 	if err != nil {
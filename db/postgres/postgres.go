@@ -27,7 +27,7 @@ func (c database) String() string {
 	return fmt.Sprintf("[Postgresql: %s]", c.dsn)
 }
 
-// Check implements checker interface for convenient use in HealthChecks function.
+// Check implements Checker interface for convenient use in HealthChecks function.
 func (c database) Check(ctx context.Context) error {
 	conninfo, _ := dbutil.SplitDSN(c.dsn)
 	conninfo = fmt.Sprintf("%s/postgres?sslmode=disable", conninfo)
@@ -0,0 +1,98 @@
+package comptest
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ingridhq/comptest/ports"
+)
+
+// LoadEnvFile reads environment variables from dotenv file.
+// Empty lines and lines starting with "#" are skipped, values can be quoted.
+func LoadEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open env file: %w", err)
+	}
+	defer f.Close()
+
+	env := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("invalid line %d in env file %q", n, path)
+		}
+		env[strings.TrimSpace(kv[0])] = unquote(strings.TrimSpace(kv[1]))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+
+	return env, nil
+}
+
+func unquote(v string) string {
+	if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
+
+// environ returns environment variables of the binary in "key=value" form with resolved ports placeholders.
+func (cfg config) environ() ([]string, error) {
+	all := map[string]string{}
+	for _, path := range cfg.envFiles {
+		env, err := LoadEnvFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range env {
+			all[k] = v
+		}
+	}
+	for k, v := range cfg.env {
+		all[k] = v
+	}
+	for k, v := range all {
+		resolved, err := ports.Resolve(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve ports in %s: %w", k, err)
+		}
+		all[k] = resolved
+	}
+
+	keys := make([]string, 0, len(all))
+	for k := range all {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	env := make([]string, 0, len(keys))
+	for _, k := range keys {
+		env = append(env, k+"="+all[k])
+	}
+	return env, nil
+}
+
+// arguments returns command-line arguments of the binary with resolved ports placeholders.
+func (cfg config) arguments() ([]string, error) {
+	args := make([]string, 0, len(cfg.args))
+	for _, arg := range cfg.args {
+		resolved, err := ports.Resolve(arg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve ports in arguments: %w", err)
+		}
+		args = append(args, resolved)
+	}
+	return args, nil
+}
@@ -10,12 +10,17 @@ import (
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/any"
+	"github.com/ingridhq/comptest/ports"
 	"google.golang.org/grpc"
 )
 
 // MustStartGRPCServer will register and start grpc server.
+// Address can contain ports placeholders, e.g. ":{port:grpc-mock}", see ports.Resolve.
 func MustStartGRPCServer(addr string, regFn func(s *grpc.Server)) {
-	addr = cleanAddress(addr)
+	addr, err := ports.Resolve(cleanAddress(addr))
+	if err != nil {
+		log.Fatalf("failed to resolve address: %v", err)
+	}
 
 	lis, err := net.Listen("tcp", addr)
 	if err != nil {
@@ -31,9 +36,13 @@ func MustStartGRPCServer(addr string, regFn func(s *grpc.Server)) {
 }
 
 // CreateGRPCConn will create grpc conn with disabled TLS.
+// Address can contain placeholders of reserved ports, see ports.ResolveReserved.
 func CreateGRPCConn(addr string) (*grpc.ClientConn, error) {
 	connOpts := getOptionsFromAddress(addr)
-	addr = cleanAddress(addr)
+	addr, err := ports.ResolveReserved(cleanAddress(addr))
+	if err != nil {
+		return nil, fmt.Errorf("create GRPC Conn error: %w", err)
+	}
 
 	conn, err := grpc.Dial(addr, connOpts...)
 	if err != nil {
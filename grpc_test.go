@@ -0,0 +1,25 @@
+package comptest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ingridhq/comptest/ports"
+)
+
+func TestCreateGRPCConnUnknownPort(t *testing.T) {
+	_, err := CreateGRPCConn("localhost:{port:comptest-grpc-typo}?insecure=true")
+	if err == nil || !strings.Contains(err.Error(), "is not reserved") {
+		t.Errorf("CreateGRPCConn() error = %v, want not reserved", err)
+	}
+	if _, ok := ports.Get("comptest-grpc-typo"); ok {
+		t.Errorf("CreateGRPCConn() reserved the port")
+	}
+
+	ports.MustReserve("comptest-grpc-mock")
+	conn, err := CreateGRPCConn("localhost:{port:comptest-grpc-mock}?insecure=true")
+	if err != nil {
+		t.Fatalf("CreateGRPCConn() = %v", err)
+	}
+	conn.Close()
+}
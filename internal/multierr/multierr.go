@@ -0,0 +1,57 @@
+// Package multierr combines several errors into one.
+package multierr
+
+import (
+	"errors"
+	"strings"
+)
+
+// Errors combines several errors into one. errors.Is and errors.As match any of them.
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns combined errors.
+func (e Errors) Unwrap() []error {
+	return e
+}
+
+// Is reports whether any of the errors matches target. It is needed by Go versions
+// whose errors.Is doesn't support Unwrap() []error.
+func (e Errors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors which matches target. It is needed by Go versions
+// whose errors.As doesn't support Unwrap() []error.
+func (e Errors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Combine returns nil for no errors, the error itself for a single one and Errors otherwise.
+func Combine(errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	default:
+		return Errors(errs)
+	}
+}
@@ -0,0 +1,46 @@
+package multierr
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+type codeError struct {
+	code int
+}
+
+func (e *codeError) Error() string {
+	return fmt.Sprintf("code %d", e.code)
+}
+
+func TestCombine(t *testing.T) {
+	if err := Combine(nil); err != nil {
+		t.Errorf("Combine(nil) = %v, want nil", err)
+	}
+
+	single := &codeError{code: 1}
+	if err := Combine([]error{single}); err != single {
+		t.Errorf("Combine() of one error = %#v, want the error itself", err)
+	}
+
+	err := Combine([]error{errors.New("first"), fmt.Errorf("second: %w", os.ErrNotExist), &codeError{code: 4}})
+	if err.Error() != "first; second: file does not exist; code 4" {
+		t.Errorf("Error() = %q", err)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("errors.Is() didn't find wrapped error")
+	}
+	if errors.Is(err, os.ErrExist) {
+		t.Errorf("errors.Is() found error which isn't there")
+	}
+
+	var target *codeError
+	if !errors.As(err, &target) || target.code != 4 {
+		t.Errorf("errors.As() = %v, want code 4", target)
+	}
+	if unwrapped := err.(Errors).Unwrap(); len(unwrapped) != 3 {
+		t.Errorf("Unwrap() returned %d errors, want 3", len(unwrapped))
+	}
+}
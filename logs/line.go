@@ -0,0 +1,123 @@
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Matcher matches lines of the output.
+type Matcher struct {
+	desc  string
+	match func(l Line) bool
+}
+
+// Match reports whether the line matches.
+func (m Matcher) Match(l Line) bool {
+	return m.match(l)
+}
+
+func (m Matcher) String() string {
+	return m.desc
+}
+
+// MatchFunc creates matcher from the function. Description is used in errors.
+func MatchFunc(desc string, fn func(l Line) bool) Matcher {
+	return Matcher{desc: desc, match: fn}
+}
+
+// Contains matches lines containing the substring.
+func Contains(substr string) Matcher {
+	return MatchFunc(fmt.Sprintf("[Contains: %q]", substr), func(l Line) bool {
+		return strings.Contains(l.Text, substr)
+	})
+}
+
+// Regexp matches lines matching the regular expression. It panics when the expression is invalid.
+func Regexp(expr string) Matcher {
+	re := regexp.MustCompile(expr)
+	return MatchFunc(fmt.Sprintf("[Regexp: %s]", expr), func(l Line) bool {
+		return re.MatchString(l.Text)
+	})
+}
+
+// Level matches lines with the log level, e.g. "error". Comparison is case insensitive.
+func Level(level string) Matcher {
+	level = normalizeLevel(level)
+	return MatchFunc(fmt.Sprintf("[Level: %s]", level), func(l Line) bool {
+		return l.Level == level
+	})
+}
+
+// Message matches JSON lines with message (field "msg" or "message") containing the substring.
+func Message(substr string) Matcher {
+	return MatchFunc(fmt.Sprintf("[Message: %q]", substr), func(l Line) bool {
+		for _, key := range []string{"msg", "message"} {
+			if msg, ok := l.Fields[key].(string); ok && strings.Contains(msg, substr) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Field matches JSON lines with the field equal to the value.
+// Values are compared by their string representation, so Field("status", 200) matches number 200.
+func Field(key string, value interface{}) Matcher {
+	want := fmt.Sprint(value)
+	return MatchFunc(fmt.Sprintf("[Field: %s=%v]", key, value), func(l Line) bool {
+		v, ok := l.Fields[key]
+		return ok && fmt.Sprint(v) == want
+	})
+}
+
+// And matches lines matching all matchers.
+func And(matchers ...Matcher) Matcher {
+	return MatchFunc(fmt.Sprintf("[And: %v]", matchers), func(l Line) bool {
+		for _, m := range matchers {
+			if !m.Match(l) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+var levelKeys = []string{"level", "lvl", "severity"}
+
+// plainLevel recognizes levels in plain text lines like "level=error", "[ERROR]" or "ERROR:".
+var plainLevel = regexp.MustCompile(`\b(?:level|lvl)=(\w+)|\b(DEBUG|INFO|WARN|WARNING|ERROR|FATAL|PANIC)\b`)
+
+func parseLine(num int, text string) Line {
+	l := Line{Num: num, Text: text, Received: time.Now()}
+
+	trimmed := strings.TrimSpace(text)
+	if strings.HasPrefix(trimmed, "{") {
+		fields := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(trimmed), &fields); err == nil {
+			l.Fields = fields
+			for _, key := range levelKeys {
+				if lvl, ok := fields[key].(string); ok {
+					l.Level = normalizeLevel(lvl)
+					break
+				}
+			}
+			return l
+		}
+	}
+
+	if m := plainLevel.FindStringSubmatch(text); m != nil {
+		l.Level = normalizeLevel(m[1] + m[2])
+	}
+	return l
+}
+
+func normalizeLevel(level string) string {
+	level = strings.ToLower(level)
+	if level == "warning" {
+		return "warn"
+	}
+	return level
+}
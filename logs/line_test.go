@@ -0,0 +1,77 @@
+package logs
+
+import "testing"
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		text   string
+		level  string
+		fields bool
+	}{
+		{text: `{"level":"ERROR","msg":"failed"}`, level: "error", fields: true},
+		{text: `  {"severity":"warning","message":"slow"}`, level: "warn", fields: true},
+		{text: `{"lvl":"info","level":1}`, level: "info", fields: true},
+		{text: `{"msg":"no level"}`, level: "", fields: true},
+		{text: `{not json ERROR`, level: "error"},
+		{text: `time=2021-01-01 level=debug msg=started`, level: "debug"},
+		{text: `2021/01/01 [WARNING] disk is almost full`, level: "warn"},
+		{text: `FATAL: can't connect`, level: "fatal"},
+		{text: `errors are not levels`, level: ""},
+		{text: ``, level: ""},
+	}
+
+	for _, tt := range tests {
+		l := parseLine(3, tt.text)
+		if l.Num != 3 || l.Text != tt.text {
+			t.Errorf("parseLine(%q) = %d %q, want 3 %q", tt.text, l.Num, l.Text, tt.text)
+		}
+		if l.Level != tt.level {
+			t.Errorf("parseLine(%q).Level = %q, want %q", tt.text, l.Level, tt.level)
+		}
+		if (l.Fields != nil) != tt.fields {
+			t.Errorf("parseLine(%q).Fields = %v, want fields: %v", tt.text, l.Fields, tt.fields)
+		}
+	}
+}
+
+func TestMatchers(t *testing.T) {
+	jsonLine := parseLine(0, `{"level":"error","msg":"request failed","status":500,"path":"/users"}`)
+	textLine := parseLine(1, `level=info server started on :8080`)
+
+	tests := []struct {
+		m    Matcher
+		line Line
+		want bool
+	}{
+		{m: Contains("started"), line: textLine, want: true},
+		{m: Contains("stopped"), line: textLine, want: false},
+		{m: Regexp(`on :\d+$`), line: textLine, want: true},
+		{m: Regexp(`^server`), line: textLine, want: false},
+		{m: Level("ERROR"), line: jsonLine, want: true},
+		{m: Level("warning"), line: jsonLine, want: false},
+		{m: Level("info"), line: textLine, want: true},
+		{m: Message("failed"), line: jsonLine, want: true},
+		{m: Message("started"), line: textLine, want: false},
+		{m: Field("status", 500), line: jsonLine, want: true},
+		{m: Field("status", "500"), line: jsonLine, want: true},
+		{m: Field("status", 200), line: jsonLine, want: false},
+		{m: Field("path", "/users"), line: textLine, want: false},
+		{m: And(Level("error"), Field("path", "/users")), line: jsonLine, want: true},
+		{m: And(Level("error"), Contains("started")), line: jsonLine, want: false},
+		{m: And(), line: textLine, want: true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.m.Match(tt.line); got != tt.want {
+			t.Errorf("%v.Match(%q) = %v, want %v", tt.m, tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestMatcherString(t *testing.T) {
+	m := And(Contains("ready"), Level("Warning"))
+	want := `[And: [[Contains: "ready"] [Level: warn]]]`
+	if m.String() != want {
+		t.Errorf("String() = %s, want %s", m, want)
+	}
+}
@@ -0,0 +1,195 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Line is a single line of the binary output.
+type Line struct {
+	// Num is position of the line in the output, starting from 0.
+	Num  int
+	Text string
+	// Fields are parsed from JSON structured logs (zap, slog, logrus...). Nil for plain text lines.
+	Fields map[string]interface{}
+	// Level is a lowercase log level, e.g. "error". Empty when it can't be recognized.
+	Level string
+	// Received is time when the line was written by the binary.
+	Received time.Time
+}
+
+func (l Line) String() string {
+	return l.Text
+}
+
+// Mark is a position in the output. Lines written after the mark have Num >= Mark.
+type Mark int
+
+// Watcher collects output of the binary line by line and allows to make assertions on it.
+// It implements io.Writer, so it can receive output of the binary directly.
+type Watcher struct {
+	mtx     sync.Mutex
+	lines   []Line
+	partial []byte
+	// changed is closed and replaced when new lines are written.
+	changed chan struct{}
+}
+
+// NewWatcher creates empty watcher.
+func NewWatcher() *Watcher {
+	return &Watcher{changed: make(chan struct{})}
+}
+
+// Write splits output into lines and parses them.
+func (w *Watcher) Write(p []byte) (int, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	w.partial = append(w.partial, p...)
+	added := false
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		text := strings.TrimRight(string(w.partial[:i]), "\r")
+		w.partial = w.partial[i+1:]
+
+		w.lines = append(w.lines, parseLine(len(w.lines), text))
+		added = true
+	}
+
+	if added {
+		close(w.changed)
+		w.changed = make(chan struct{})
+	}
+	return len(p), nil
+}
+
+// Mark returns current position in the output.
+func (w *Watcher) Mark() Mark {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return Mark(len(w.lines))
+}
+
+// All returns cursor over the whole output.
+func (w *Watcher) All() Cursor {
+	return Cursor{w: w, from: 0, to: -1}
+}
+
+// Since returns cursor over lines written after the mark.
+func (w *Watcher) Since(m Mark) Cursor {
+	return Cursor{w: w, from: int(m), to: -1}
+}
+
+// Between returns cursor over lines written between the marks.
+func (w *Watcher) Between(from, to Mark) Cursor {
+	return Cursor{w: w, from: int(from), to: int(to)}
+}
+
+// Lines returns all lines written so far.
+func (w *Watcher) Lines() []Line {
+	return w.All().Lines()
+}
+
+// WaitForLine waits until a line matching the matcher is written. Lines written before are also checked.
+func (w *Watcher) WaitForLine(ctx context.Context, m Matcher) (Line, error) {
+	return w.All().WaitForLine(ctx, m)
+}
+
+// AssertNoLevel fails the test when any line has given level.
+func (w *Watcher) AssertNoLevel(t testing.TB, level string) {
+	t.Helper()
+	w.All().AssertNoLevel(t, level)
+}
+
+// Cursor is a view of the part of the output.
+type Cursor struct {
+	w    *Watcher
+	from int
+	// to is -1 when cursor is open-ended.
+	to int
+}
+
+// lines returns lines of the cursor and channel closed when new lines are written.
+func (c Cursor) lines() ([]Line, <-chan struct{}) {
+	c.w.mtx.Lock()
+	defer c.w.mtx.Unlock()
+
+	to := len(c.w.lines)
+	if c.to >= 0 && c.to < to {
+		to = c.to
+	}
+	if c.from >= to {
+		return nil, c.w.changed
+	}
+	return append([]Line(nil), c.w.lines[c.from:to]...), c.w.changed
+}
+
+// Lines returns lines of the cursor written so far.
+func (c Cursor) Lines() []Line {
+	lines, _ := c.lines()
+	return lines
+}
+
+// Find returns lines matching the matcher.
+func (c Cursor) Find(m Matcher) []Line {
+	var found []Line
+	for _, l := range c.Lines() {
+		if m.Match(l) {
+			found = append(found, l)
+		}
+	}
+	return found
+}
+
+// WaitForLine waits until a line matching the matcher is written within the cursor.
+func (c Cursor) WaitForLine(ctx context.Context, m Matcher) (Line, error) {
+	checked := 0
+	for {
+		lines, changed := c.lines()
+		for _, l := range lines[checked:] {
+			if m.Match(l) {
+				return l, nil
+			}
+		}
+		checked = len(lines)
+
+		if c.to >= 0 && c.from+checked >= c.to {
+			return Line{}, fmt.Errorf("no line matching %v between marks", m)
+		}
+
+		select {
+		case <-ctx.Done():
+			return Line{}, fmt.Errorf("no line matching %v: %w", m, ctx.Err())
+		case <-changed:
+		}
+	}
+}
+
+// AssertNoLevel fails the test when any line of the cursor has given level.
+func (c Cursor) AssertNoLevel(t testing.TB, level string) {
+	t.Helper()
+	c.AssertNone(t, Level(level))
+}
+
+// AssertNone fails the test when any line of the cursor matches the matcher.
+func (c Cursor) AssertNone(t testing.TB, m Matcher) {
+	t.Helper()
+	found := c.Find(m)
+	if len(found) == 0 {
+		return
+	}
+
+	texts := make([]string, 0, len(found))
+	for _, l := range found {
+		texts = append(texts, l.Text)
+	}
+	t.Errorf("found %d unexpected log lines matching %v:\n%s", len(found), m, strings.Join(texts, "\n"))
+}
@@ -0,0 +1,161 @@
+package logs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatcherWrite(t *testing.T) {
+	w := NewWatcher()
+	for _, chunk := range []string{"fir", "st\r\nsec", "ond\n\nthi", "rd"} {
+		n, err := fmt.Fprint(w, chunk)
+		if n != len(chunk) || err != nil {
+			t.Fatalf("Write(%q) = %d, %v", chunk, n, err)
+		}
+	}
+
+	got := texts(w.Lines())
+	want := []string{"first", "second", ""}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("Lines() = %q, want %q", got, want)
+	}
+	for i, l := range w.Lines() {
+		if l.Num != i {
+			t.Errorf("line %q has Num %d, want %d", l.Text, l.Num, i)
+		}
+	}
+
+	fmt.Fprint(w, "\n")
+	if got := texts(w.Lines()); len(got) != 4 || got[3] != "third" {
+		t.Errorf("Lines() = %q, want partial line completed", got)
+	}
+}
+
+func TestCursors(t *testing.T) {
+	w := NewWatcher()
+	fmt.Fprint(w, "a\nb\n")
+	m1 := w.Mark()
+	fmt.Fprint(w, "c\nd\n")
+	m2 := w.Mark()
+	fmt.Fprint(w, "e\n")
+
+	tests := []struct {
+		name string
+		c    Cursor
+		want string
+	}{
+		{name: "All", c: w.All(), want: "a b c d e"},
+		{name: "Since", c: w.Since(m1), want: "c d e"},
+		{name: "Between", c: w.Between(m1, m2), want: "c d"},
+		{name: "Between empty", c: w.Between(m2, m1), want: ""},
+		{name: "Since end", c: w.Since(w.Mark()), want: ""},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(texts(tt.c.Lines()), " "); got != tt.want {
+			t.Errorf("%s: Lines() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	found := w.Since(m1).Find(Regexp(`^[ae]$`))
+	if got := strings.Join(texts(found), " "); got != "e" {
+		t.Errorf("Find() = %q, want %q", got, "e")
+	}
+}
+
+func TestWaitForLine(t *testing.T) {
+	w := NewWatcher()
+	fmt.Fprint(w, "starting\n")
+	mark := w.Mark()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Lines written before are checked too.
+	if l, err := w.WaitForLine(ctx, Contains("starting")); err != nil || l.Num != 0 {
+		t.Fatalf("WaitForLine() = %v, %v, want line 0", l, err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		fmt.Fprint(w, "loading\n")
+		time.Sleep(10 * time.Millisecond)
+		fmt.Fprint(w, "ready\n")
+	}()
+	l, err := w.Since(mark).WaitForLine(ctx, Contains("ready"))
+	if err != nil || l.Text != "ready" || l.Num != 2 {
+		t.Fatalf("WaitForLine() = %v %d, %v, want ready 2", l, l.Num, err)
+	}
+}
+
+func TestWaitForLineBetween(t *testing.T) {
+	w := NewWatcher()
+	from := w.Mark()
+	fmt.Fprint(w, "a\nb\n")
+	to := w.Mark()
+	fmt.Fprint(w, "c\n")
+
+	// Closed cursor fails at once instead of waiting for the context.
+	_, err := w.Between(from, to).WaitForLine(context.Background(), Contains("c"))
+	if err == nil || !strings.Contains(err.Error(), "between marks") {
+		t.Errorf("WaitForLine() error = %v, want no line between marks", err)
+	}
+
+	l, err := w.Between(from, to).WaitForLine(context.Background(), Contains("b"))
+	if err != nil || l.Text != "b" {
+		t.Errorf("WaitForLine() = %v, %v, want b", l, err)
+	}
+}
+
+func TestWaitForLineContextDone(t *testing.T) {
+	w := NewWatcher()
+	fmt.Fprint(w, "a\n")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := w.WaitForLine(ctx, Contains("never"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitForLine() error = %v, want deadline exceeded", err)
+	}
+}
+
+func TestAssertNone(t *testing.T) {
+	w := NewWatcher()
+	fmt.Fprint(w, "level=info ok\nlevel=error boom\n")
+
+	rec := &recordingT{TB: t}
+	w.AssertNoLevel(rec, "warn")
+	if rec.failed {
+		t.Errorf("AssertNoLevel(warn) failed: %s", rec.msg)
+	}
+
+	w.AssertNoLevel(rec, "ERROR")
+	if !rec.failed || !strings.Contains(rec.msg, "level=error boom") {
+		t.Errorf("AssertNoLevel(error) = %v %q, want failure with the line", rec.failed, rec.msg)
+	}
+}
+
+// recordingT records failures instead of failing the test.
+type recordingT struct {
+	testing.TB
+	failed bool
+	msg    string
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.failed = true
+	r.msg = fmt.Sprintf(format, args...)
+}
+
+func texts(lines []Line) []string {
+	texts := make([]string, 0, len(lines))
+	for _, l := range lines {
+		texts = append(texts, l.Text)
+	}
+	return texts
+}
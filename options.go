@@ -0,0 +1,249 @@
+package comptest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ingridhq/comptest/binary"
+	"github.com/ingridhq/comptest/waitfor"
+)
+
+// Option configures comptest suite. Options passed to Run and BuildAndRun apply only to that binary.
+type Option func(cfg *config)
+
+type config struct {
+	binaryPath      string
+	logsPath        string
+	args            []string
+	env             map[string]string
+	envFiles        []string
+	isolateEnv      bool
+	workDir         string
+	build           binary.BuildOptions
+	retryPolicy     waitfor.RetryPolicy
+	reporter        Reporter
+	shutdownSignal  os.Signal
+	shutdownTimeout time.Duration
+	cleanShutdown   bool
+	coverProfile    string
+	coverPackages   []string
+	pidFile         string
+	onStale         binary.StaleAction
+	stderrPath      string
+	keepLogs        int
+	tee             io.Writer
+	teePrefix       string
+	logsTail        int
+}
+
+func defaultConfig() config {
+	return config{
+		binaryPath:      defaultBinaryPath(),
+		logsPath:        "./comptest.log",
+		env:             map[string]string{},
+		retryPolicy:     waitfor.DefaultRetryPolicy(),
+		reporter:        LogReporter(log.Default()),
+		shutdownTimeout: 10 * time.Second,
+		logsTail:        20,
+	}
+}
+
+// defaultBinaryPath returns path of the binary unique for the package being tested,
+// so tests of different packages can run in parallel.
+func defaultBinaryPath() string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "main")
+	}
+	sum := sha256.Sum256([]byte(wd))
+	return filepath.Join(os.TempDir(), "comptest-"+hex.EncodeToString(sum[:4]), "main")
+}
+
+// with returns copy of the config with applied options.
+func (cfg config) with(opts ...Option) config {
+	env := make(map[string]string, len(cfg.env))
+	for k, v := range cfg.env {
+		env[k] = v
+	}
+	cfg.env = env
+	cfg.args = append([]string(nil), cfg.args...)
+	cfg.envFiles = append([]string(nil), cfg.envFiles...)
+	cfg.build.Tags = append([]string(nil), cfg.build.Tags...)
+	cfg.build.Flags = append([]string(nil), cfg.build.Flags...)
+	cfg.build.Env = append([]string(nil), cfg.build.Env...)
+	cfg.coverPackages = append([]string(nil), cfg.coverPackages...)
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// WithBinaryPath sets custom path where binary will be build.
+func WithBinaryPath(binaryPath string) Option {
+	return func(cfg *config) {
+		cfg.binaryPath = binaryPath
+	}
+}
+
+// WithLogsPath sets custom file to store logs from binary.
+func WithLogsPath(logsPath string) Option {
+	return func(cfg *config) {
+		cfg.logsPath = logsPath
+	}
+}
+
+// WithStderrPath sets custom file to store standard error of binary.
+// By default it is stored together with standard output.
+func WithStderrPath(stderrPath string) Option {
+	return func(cfg *config) {
+		cfg.stderrPath = stderrPath
+	}
+}
+
+// WithKeepLogs keeps logs files of given number of previous runs with ".1", ".2"... suffixes.
+func WithKeepLogs(keep int) Option {
+	return func(cfg *config) {
+		cfg.keepLogs = keep
+	}
+}
+
+// WithLogsTee copies output of binary to w (e.g. os.Stdout), every line is prefixed with prefix.
+func WithLogsTee(w io.Writer, prefix string) Option {
+	return func(cfg *config) {
+		cfg.tee = w
+		cfg.teePrefix = prefix
+	}
+}
+
+// WithLogsTail sets number of last lines of binary logs attached to failed tests. Defaults to 20.
+// Zero or less means logs are not attached.
+func WithLogsTail(lines int) Option {
+	return func(cfg *config) {
+		cfg.logsTail = lines
+	}
+}
+
+// WithArgs sets command-line arguments of the binary.
+func WithArgs(args ...string) Option {
+	return func(cfg *config) {
+		cfg.args = args
+	}
+}
+
+// WithEnv adds environment variables passed to the binary.
+// They take precedence over variables loaded with WithEnvFile.
+func WithEnv(env map[string]string) Option {
+	return func(cfg *config) {
+		for k, v := range env {
+			cfg.env[k] = v
+		}
+	}
+}
+
+// WithEnvFile loads environment variables of the binary from dotenv file.
+// Files are loaded in order, so variables from later files take precedence.
+func WithEnvFile(path string) Option {
+	return func(cfg *config) {
+		cfg.envFiles = append(cfg.envFiles, path)
+	}
+}
+
+// WithIsolatedEnv makes binary not inherit environment of the test process.
+// Only variables set with WithEnv and WithEnvFile are passed to the binary.
+func WithIsolatedEnv() Option {
+	return func(cfg *config) {
+		cfg.isolateEnv = true
+	}
+}
+
+// WithWorkDir sets working directory of the binary.
+func WithWorkDir(dir string) Option {
+	return func(cfg *config) {
+		cfg.workDir = dir
+	}
+}
+
+// WithBuildFlags adds flags passed to "go build" command, e.g. "-gcflags=all=-N -l".
+func WithBuildFlags(flags ...string) Option {
+	return func(cfg *config) {
+		cfg.build.Flags = append(cfg.build.Flags, flags...)
+	}
+}
+
+// WithBuildOptions sets options of "go build" command like tags, ldflags, race detector or build caching.
+// Options are merged with those set before, regardless of the order: tags, flags and environment
+// are appended, other fields override previous values when they are set.
+func WithBuildOptions(opts binary.BuildOptions) Option {
+	return func(cfg *config) {
+		cfg.build.Tags = append(cfg.build.Tags, opts.Tags...)
+		cfg.build.Flags = append(cfg.build.Flags, opts.Flags...)
+		cfg.build.Env = append(cfg.build.Env, opts.Env...)
+		if opts.LDFlags != "" {
+			cfg.build.LDFlags = opts.LDFlags
+		}
+		if opts.Dir != "" {
+			cfg.build.Dir = opts.Dir
+		}
+		cfg.build.Race = cfg.build.Race || opts.Race
+		cfg.build.TrimPath = cfg.build.TrimPath || opts.TrimPath
+		cfg.build.Cache = cfg.build.Cache || opts.Cache
+	}
+}
+
+// WithPIDFile sets file storing PID of the running binary.
+// Defaults to a file in the temporary directory unique for the path of the binary, see binary.DefaultPIDFile.
+func WithPIDFile(path string) Option {
+	return func(cfg *config) {
+		cfg.pidFile = path
+	}
+}
+
+// WithStaleBinary sets what to do when binary left by previously crashed run is still running.
+// By default the suite refuses to start.
+func WithStaleBinary(action binary.StaleAction) Option {
+	return func(cfg *config) {
+		cfg.onStale = action
+	}
+}
+
+// WithRetryPolicy sets policy used to retry checks.
+func WithRetryPolicy(policy waitfor.RetryPolicy) Option {
+	return func(cfg *config) {
+		cfg.retryPolicy = policy
+	}
+}
+
+// WithReporter sets reporter which receives progress of health checks.
+func WithReporter(reporter Reporter) Option {
+	return func(cfg *config) {
+		cfg.reporter = reporter
+	}
+}
+
+// WithShutdownSignal sets signal sent to the binary on cleanup. Defaults to SIGTERM.
+func WithShutdownSignal(sig os.Signal) Option {
+	return func(cfg *config) {
+		cfg.shutdownSignal = sig
+	}
+}
+
+// WithShutdownTimeout sets grace period for the binary to exit after shutdown signal.
+// When it passes, the binary is killed. Zero means the binary is killed immediately.
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(cfg *config) {
+		cfg.shutdownTimeout = timeout
+	}
+}
+
+// WithRequireCleanShutdown makes cleanup fail when the binary doesn't exit with code 0 after shutdown signal.
+func WithRequireCleanShutdown() Option {
+	return func(cfg *config) {
+		cfg.cleanShutdown = true
+	}
+}
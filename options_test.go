@@ -0,0 +1,59 @@
+package comptest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ingridhq/comptest/binary"
+)
+
+func TestBuildOptionsOrder(t *testing.T) {
+	flags := WithBuildFlags("-gcflags=all=-N -l")
+	opts := WithBuildOptions(binary.BuildOptions{
+		Tags:    []string{"integration"},
+		LDFlags: "-s -w",
+		Flags:   []string{"-v"},
+		Cache:   true,
+	})
+
+	for _, cfg := range []config{defaultConfig().with(flags, opts), defaultConfig().with(opts, flags)} {
+		if got := strings.Join(cfg.build.Flags, " "); !strings.Contains(got, "-gcflags=all=-N -l") || !strings.Contains(got, "-v") {
+			t.Errorf("Flags = %q, want flags of both options", cfg.build.Flags)
+		}
+		if cfg.build.LDFlags != "-s -w" || !cfg.build.Cache || len(cfg.build.Tags) != 1 {
+			t.Errorf("build options = %+v, want LDFlags, Cache and Tags", cfg.build)
+		}
+	}
+
+	cfg := defaultConfig().with(
+		WithBuildOptions(binary.BuildOptions{LDFlags: "-s", Race: true}),
+		WithBuildOptions(binary.BuildOptions{Tags: []string{"a"}}),
+		WithBuildOptions(binary.BuildOptions{LDFlags: "-w", Tags: []string{"b"}}),
+	)
+	if cfg.build.LDFlags != "-w" || !cfg.build.Race || strings.Join(cfg.build.Tags, ",") != "a,b" {
+		t.Errorf("build options = %+v, want merged options", cfg.build)
+	}
+}
+
+func TestConfigWithCopies(t *testing.T) {
+	base := defaultConfig().with(
+		WithBuildFlags("-a"),
+		WithArgs("--port", "1"),
+		WithEnv(map[string]string{"A": "1"}),
+	)
+	derived := base.with(
+		WithBuildFlags("-b"),
+		WithArgs("--debug"),
+		WithEnv(map[string]string{"A": "2", "B": "3"}),
+	)
+
+	if strings.Join(base.build.Flags, " ") != "-a" || strings.Join(base.args, " ") != "--port 1" {
+		t.Errorf("base config was changed: flags %q, args %q", base.build.Flags, base.args)
+	}
+	if base.env["A"] != "1" || base.env["B"] != "" {
+		t.Errorf("base env was changed: %v", base.env)
+	}
+	if strings.Join(derived.build.Flags, " ") != "-a -b" || strings.Join(derived.args, " ") != "--debug" {
+		t.Errorf("derived config: flags %q, args %q", derived.build.Flags, derived.args)
+	}
+}
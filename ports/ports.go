@@ -0,0 +1,128 @@
+// Package ports allocates free TCP ports for binaries and mocks run by component tests,
+// so tests of several packages can run in parallel without collisions.
+//
+// Ports are reserved by name and shared by the whole test process. Addresses, environment
+// variables and arguments can refer to them with {port:name} placeholders, e.g. "localhost:{port:api}".
+// Other braces, e.g. "/users/{id}", are left as they are.
+package ports
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+var (
+	mtx      sync.Mutex
+	reserved = map[string]int{}
+	used     = map[int]bool{}
+)
+
+var placeholder = regexp.MustCompile(`\{port:([A-Za-z0-9_.-]+)\}`)
+
+// Reserve returns free TCP port reserved for the name. The same name always gets the same port.
+// The port is free when it is reserved, but it is not held, so other processes can take it before it's used.
+func Reserve(name string) (int, error) {
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	if port, ok := reserved[name]; ok {
+		return port, nil
+	}
+
+	// Port released by this process could be given again, so it's retried a few times.
+	for i := 0; i < 10; i++ {
+		port, err := freePort()
+		if err != nil {
+			return 0, fmt.Errorf("couldn't reserve port %q: %w", name, err)
+		}
+		if !used[port] {
+			reserved[name] = port
+			used[port] = true
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("couldn't reserve port %q: no free port found", name)
+}
+
+// MustReserve works like Reserve, but panics on error.
+func MustReserve(name string) int {
+	port, err := Reserve(name)
+	if err != nil {
+		panic(err)
+	}
+	return port
+}
+
+// Get returns port reserved for the name.
+func Get(name string) (int, bool) {
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	port, ok := reserved[name]
+	return port, ok
+}
+
+// Addr returns local address with port reserved for the name, e.g. "127.0.0.1:41234".
+func Addr(name string) (string, error) {
+	port, err := Reserve(name)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), nil
+}
+
+// Resolve replaces {port:name} placeholders in s with ports reserved for the names,
+// e.g. "http://localhost:{port:api}/health". Ports which are not reserved yet are reserved.
+func Resolve(s string) (string, error) {
+	return replace(s, Reserve)
+}
+
+// ResolveReserved works like Resolve, but fails when a port is not reserved yet.
+// It's used by checks, which connect to ports reserved by binaries and mocks,
+// so a typo in the name doesn't make them wait for a port nobody listens on.
+func ResolveReserved(s string) (string, error) {
+	return replace(s, func(name string) (int, error) {
+		port, ok := Get(name)
+		if !ok {
+			return 0, fmt.Errorf("port %q is not reserved", name)
+		}
+		return port, nil
+	})
+}
+
+// MustResolve works like Resolve, but panics on error.
+func MustResolve(s string) string {
+	resolved, err := Resolve(s)
+	if err != nil {
+		panic(err)
+	}
+	return resolved
+}
+
+func replace(s string, port func(name string) (int, error)) (string, error) {
+	var err error
+	resolved := placeholder.ReplaceAllStringFunc(s, func(p string) string {
+		if err != nil {
+			return p
+		}
+		var n int
+		n, err = port(placeholder.FindStringSubmatch(p)[1])
+		if err != nil {
+			return p
+		}
+		return strconv.Itoa(n)
+	})
+	return resolved, err
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
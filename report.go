@@ -0,0 +1,173 @@
+package comptest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"text/tabwriter"
+	"time"
+)
+
+// Event describes a single attempt of a health check.
+type Event struct {
+	Check   string
+	Attempt int
+	// Err is nil when the attempt succeeded.
+	Err error
+	// Elapsed is time since the suite started waiting for the check.
+	Elapsed time.Duration
+}
+
+// Ready reports whether the check succeeded in this attempt.
+func (e Event) Ready() bool {
+	return e.Err == nil
+}
+
+// Result describes how long a check took to become ready.
+type Result struct {
+	Check    string
+	Attempts int
+	Elapsed  time.Duration
+	// Err is the last error of the check. It is nil when the check became ready.
+	Err error
+}
+
+// Reporter receives progress of health checks.
+// Methods can be called concurrently.
+type Reporter interface {
+	// Attempt is called after every attempt of every check.
+	Attempt(e Event)
+	// Summary is called when waiting for a group of checks is finished.
+	Summary(results []Result)
+}
+
+// LogReporter reports progress of health checks to the logger.
+func LogReporter(l *log.Logger) Reporter {
+	return printfReporter{printf: l.Printf}
+}
+
+// TBReporter reports progress of health checks to the test log.
+func TBReporter(tb testing.TB) Reporter {
+	return printfReporter{printf: tb.Logf}
+}
+
+// JSONReporter writes progress of health checks as JSON lines.
+func JSONReporter(w io.Writer) Reporter {
+	return &jsonReporter{enc: json.NewEncoder(w)}
+}
+
+// NopReporter discards progress of health checks.
+func NopReporter() Reporter {
+	return nopReporter{}
+}
+
+type printfReporter struct {
+	printf func(format string, args ...interface{})
+}
+
+func (r printfReporter) Attempt(e Event) {
+	if e.Ready() {
+		r.printf("%s ready after %v (attempt %d)", e.Check, roundDuration(e.Elapsed), e.Attempt)
+		return
+	}
+	r.printf("%s not ready after %v (attempt %d): %v", e.Check, roundDuration(e.Elapsed), e.Attempt, e.Err)
+}
+
+func (r printfReporter) Summary(results []Result) {
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tSTATUS\tATTEMPTS\tELAPSED")
+	for _, res := range results {
+		status := "ready"
+		if res.Err != nil {
+			status = "failed"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%v\n", res.Check, status, res.Attempts, roundDuration(res.Elapsed))
+	}
+	w.Flush()
+
+	r.printf("Health checks summary:\n%s", buf.String())
+}
+
+type jsonReporter struct {
+	mtx sync.Mutex
+	enc *json.Encoder
+}
+
+type jsonResult struct {
+	Check     string  `json:"check"`
+	Attempt   int     `json:"attempt,omitempty"`
+	Attempts  int     `json:"attempts,omitempty"`
+	Ready     bool    `json:"ready"`
+	Error     string  `json:"error,omitempty"`
+	ElapsedMs float64 `json:"elapsed_ms"`
+}
+
+func (r *jsonReporter) Attempt(e Event) {
+	r.encode(struct {
+		Type string `json:"type"`
+		jsonResult
+	}{
+		Type: "attempt",
+		jsonResult: jsonResult{
+			Check:     e.Check,
+			Attempt:   e.Attempt,
+			Ready:     e.Ready(),
+			Error:     errorString(e.Err),
+			ElapsedMs: milliseconds(e.Elapsed),
+		},
+	})
+}
+
+func (r *jsonReporter) Summary(results []Result) {
+	out := make([]jsonResult, 0, len(results))
+	for _, res := range results {
+		out = append(out, jsonResult{
+			Check:     res.Check,
+			Attempts:  res.Attempts,
+			Ready:     res.Err == nil,
+			Error:     errorString(res.Err),
+			ElapsedMs: milliseconds(res.Elapsed),
+		})
+	}
+	r.encode(struct {
+		Type    string       `json:"type"`
+		Results []jsonResult `json:"results"`
+	}{
+		Type:    "summary",
+		Results: out,
+	})
+}
+
+func (r *jsonReporter) encode(v interface{}) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if err := r.enc.Encode(v); err != nil {
+		log.Printf("Failed to report health check progress: %v", err)
+	}
+}
+
+type nopReporter struct{}
+
+func (nopReporter) Attempt(Event)    {}
+func (nopReporter) Summary([]Result) {}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func roundDuration(d time.Duration) time.Duration {
+	return d.Round(time.Millisecond)
+}
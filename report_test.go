@@ -0,0 +1,143 @@
+package comptest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ingridhq/comptest/waitfor"
+)
+
+// checkerFunc is a named check which runs the function.
+type checkerFunc struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+func (c checkerFunc) String() string {
+	return c.name
+}
+
+func (c checkerFunc) Check(ctx context.Context) error {
+	return c.fn(ctx)
+}
+
+// readyAfter returns check which fails n times before it succeeds.
+func readyAfter(name string, n int32) Checker {
+	var attempts int32
+	return checkerFunc{name: name, fn: func(ctx context.Context) error {
+		if atomic.AddInt32(&attempts, 1) <= n {
+			return errors.New("not ready")
+		}
+		return nil
+	}}
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestJSONReporter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	c := New(testContext(t),
+		WithReporter(JSONReporter(buf)),
+		WithRetryPolicy(waitfor.ConstantRetryPolicy(time.Millisecond)),
+	)
+	if err := c.TryHealthChecks(readyAfter("[db]", 2)); err != nil {
+		t.Fatal(err)
+	}
+
+	type line struct {
+		Type     string `json:"type"`
+		Check    string `json:"check"`
+		Attempt  int    `json:"attempt"`
+		Ready    bool   `json:"ready"`
+		Error    string `json:"error"`
+		Attempts int    `json:"attempts"`
+		Results  []line `json:"results"`
+	}
+	var lines []line
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var l line
+		if err := dec.Decode(&l); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, l)
+	}
+
+	if len(lines) != 4 {
+		t.Fatalf("got %d JSON lines, want 3 attempts and summary: %+v", len(lines), lines)
+	}
+	for i, l := range lines[:3] {
+		ready := i == 2
+		if l.Type != "attempt" || l.Check != "[db]" || l.Attempt != i+1 || l.Ready != ready || (l.Error == "") != ready {
+			t.Errorf("attempt %d = %+v", i+1, l)
+		}
+	}
+	summary := lines[3]
+	if summary.Type != "summary" || len(summary.Results) != 1 {
+		t.Fatalf("summary = %+v", summary)
+	}
+	if res := summary.Results[0]; res.Check != "[db]" || !res.Ready || res.Attempts != 3 {
+		t.Errorf("result = %+v, want ready after 3 attempts", res)
+	}
+}
+
+func TestLogReporter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	c := New(testContext(t),
+		WithReporter(LogReporter(log.New(buf, "", 0))),
+		WithRetryPolicy(waitfor.RetryPolicy{InitialInterval: time.Millisecond, MaxAttempts: 2}),
+	)
+	err := c.TryHealthChecks(readyAfter("[db]", 1), readyAfter("[pubsub]", 5))
+	if err == nil {
+		t.Fatalf("TryHealthChecks() succeeded")
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"[db] not ready after",
+		"(attempt 1): not ready",
+		"[db] ready after",
+		"(attempt 2)",
+		"Health checks summary:",
+		"CHECK",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output doesn't contain %q:\n%s", want, out)
+		}
+	}
+	if !containsLine(out, "[db]", "ready", "2") || !containsLine(out, "[pubsub]", "failed", "2") {
+		t.Errorf("summary doesn't contain results of the checks:\n%s", out)
+	}
+}
+
+func TestNopReporter(t *testing.T) {
+	r := NopReporter()
+	r.Attempt(Event{Check: "[db]", Attempt: 1})
+	r.Summary([]Result{{Check: "[db]"}})
+
+	if !(Event{}).Ready() || (Event{Err: errors.New("not ready")}).Ready() {
+		t.Errorf("Event.Ready() doesn't match Err")
+	}
+}
+
+// containsLine reports whether any line of out starts with the fields, ignoring whitespace between them.
+func containsLine(out string, fields ...string) bool {
+	for _, line := range strings.Split(out, "\n") {
+		got := strings.Fields(line)
+		if len(got) >= len(fields) && strings.Join(got[:len(fields)], " ") == strings.Join(fields, " ") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package comptest
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ingridhq/comptest/binary"
+	"golang.org/x/sync/errgroup"
+)
+
+// Service describes one of the binaries run by the suite.
+type Service struct {
+	// Name identifies the service in DependsOn, logs and errors.
+	Name string
+	// BuildPath is a path to the main package. When empty, binary set with WithBinaryPath is run without building.
+	BuildPath string
+	// Readiness is checked after the service is started. Services depending on it are started when it succeeds.
+	Readiness Checker
+	// DependsOn lists names of services which must be ready before this service is started.
+	DependsOn []string
+	// Options configure the binary of the service (binary path, logs, env, args...).
+	// They override options of the suite. By default binary and logs paths of the suite
+	// are suffixed with the name of the service.
+	Options []Option
+}
+
+// StartServices builds services in parallel, starts them in order of dependencies and waits for their readiness.
+// Services are stopped in reverse order by Close.
+// On failure all registered cleanups are invoked and the process exits.
+func (c *comptest) StartServices(services ...Service) {
+	if err := c.TryStartServices(services...); err != nil {
+		c.fatal(err)
+	}
+}
+
+// TryStartServices builds services in parallel, starts them in order of dependencies and waits for their readiness.
+// Services are stopped in reverse order by Close.
+func (c *comptest) TryStartServices(services ...Service) error {
+	levels, err := dependencyLevels(services)
+	if err != nil {
+		return err
+	}
+
+	cfgs := make(map[string]config, len(services))
+	for _, svc := range services {
+		cfgs[svc.Name] = c.serviceConfig(svc)
+	}
+
+	// The first failure cancels other builds and readiness checks.
+	g, ctx := errgroup.WithContext(c.ctx)
+	for _, svc := range services {
+		svc := svc
+		if svc.BuildPath == "" {
+			continue
+		}
+		g.Go(func() error {
+			if err := build(ctx, cfgs[svc.Name], svc.BuildPath); err != nil {
+				return fmt.Errorf("service %q: %w", svc.Name, err)
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	// Services on the same level don't depend on each other, so they are started in parallel.
+	for _, level := range levels {
+		g, ctx := errgroup.WithContext(c.ctx)
+		for _, svc := range level {
+			svc := svc
+			g.Go(func() error {
+				cfg := cfgs[svc.Name]
+				_, err := c.run(ctx, svc.Name, cfg, cfg.binaryPath, svc.Readiness)
+				return err
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// serviceConfig returns configuration of the service with binary and logs paths unique for the service.
+func (c *comptest) serviceConfig(svc Service) config {
+	logsExt := filepath.Ext(c.cfg.logsPath)
+	defaults := []Option{
+		WithBinaryPath(c.cfg.binaryPath + "-" + svc.Name),
+		WithLogsPath(strings.TrimSuffix(c.cfg.logsPath, logsExt) + "-" + svc.Name + logsExt),
+	}
+	if c.cfg.stderrPath != "" {
+		stderrExt := filepath.Ext(c.cfg.stderrPath)
+		defaults = append(defaults, WithStderrPath(strings.TrimSuffix(c.cfg.stderrPath, stderrExt)+"-"+svc.Name+stderrExt))
+	}
+	// PID file is not derived from binary path, because services can run the same binary.
+	pidFile := binary.DefaultPIDFile(c.cfg.binaryPath + "-" + svc.Name)
+	if c.cfg.pidFile != "" {
+		pidFile = c.cfg.pidFile + "-" + svc.Name
+	}
+	defaults = append(defaults, WithPIDFile(pidFile))
+
+	return c.cfg.with(append(defaults, svc.Options...)...)
+}
+
+// dependencyLevels groups services so that every service depends only on services from previous groups.
+func dependencyLevels(services []Service) ([][]Service, error) {
+	byName := make(map[string]Service, len(services))
+	for _, svc := range services {
+		if svc.Name == "" {
+			return nil, fmt.Errorf("service name can't be empty")
+		}
+		if _, ok := byName[svc.Name]; ok {
+			return nil, fmt.Errorf("service %q is defined more than once", svc.Name)
+		}
+		byName[svc.Name] = svc
+	}
+	for _, svc := range services {
+		for _, dep := range svc.DependsOn {
+			if _, ok := byName[dep]; !ok {
+				return nil, fmt.Errorf("service %q depends on unknown service %q", svc.Name, dep)
+			}
+		}
+	}
+
+	var levels [][]Service
+	started := map[string]bool{}
+	for len(started) < len(services) {
+		var level []Service
+		for _, svc := range services {
+			if !started[svc.Name] && dependenciesStarted(svc, started) {
+				level = append(level, svc)
+			}
+		}
+		if len(level) == 0 {
+			return nil, fmt.Errorf("services have circular dependencies")
+		}
+		for _, svc := range level {
+			started[svc.Name] = true
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+func dependenciesStarted(svc Service, started map[string]bool) bool {
+	for _, dep := range svc.DependsOn {
+		if !started[dep] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package comptest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDependencyLevels(t *testing.T) {
+	levels, err := dependencyLevels([]Service{
+		{Name: "api", DependsOn: []string{"users", "orders"}},
+		{Name: "users"},
+		{Name: "orders", DependsOn: []string{"users"}},
+		{Name: "worker"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "[users worker] [orders] [api]"
+	if got := levelNames(levels); got != want {
+		t.Errorf("dependencyLevels() = %s, want %s", got, want)
+	}
+}
+
+func TestDependencyLevelsErrors(t *testing.T) {
+	tests := []struct {
+		services []Service
+		err      string
+	}{
+		{
+			services: []Service{{Name: ""}},
+			err:      "service name can't be empty",
+		},
+		{
+			services: []Service{{Name: "api"}, {Name: "api"}},
+			err:      `service "api" is defined more than once`,
+		},
+		{
+			services: []Service{{Name: "api", DependsOn: []string{"usres"}}},
+			err:      `service "api" depends on unknown service "usres"`,
+		},
+		{
+			services: []Service{
+				{Name: "a", DependsOn: []string{"b"}},
+				{Name: "b", DependsOn: []string{"a"}},
+				{Name: "c"},
+			},
+			err: "services have circular dependencies",
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := dependencyLevels(tt.services)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("dependencyLevels() error = %v, want %s", err, tt.err)
+		}
+	}
+}
+
+func levelNames(levels [][]Service) string {
+	var names []string
+	for _, level := range levels {
+		var svcNames []string
+		for _, svc := range level {
+			svcNames = append(svcNames, svc.Name)
+		}
+		names = append(names, fmt.Sprint(svcNames))
+	}
+	return strings.Join(names, " ")
+}
@@ -0,0 +1,211 @@
+package comptest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/ingridhq/comptest/binary"
+	"github.com/ingridhq/comptest/logs"
+	"github.com/ingridhq/comptest/waitfor"
+)
+
+// SUT is a binary (system under test) started by the suite.
+// It can be stopped, started and restarted during tests to check how the binary recovers.
+type SUT struct {
+	suite     *comptest
+	name      string
+	path      string
+	cfg       config
+	opts      binary.RunOptions
+	readiness Checker
+	logs      *logs.Watcher
+
+	mtx  sync.Mutex
+	proc *binary.Process
+	// startMark marks output of the current process in logs.
+	startMark logs.Mark
+	// started is closed when the binary is started again.
+	started chan struct{}
+	// closed is set when the binary is stopped by cleanup of the suite, it can't be started again.
+	closed bool
+}
+
+// Name returns name of the service, empty for binaries started with Run and BuildAndRun.
+func (s *SUT) Name() string {
+	return s.name
+}
+
+// Process returns the current process of the binary. It changes when the binary is restarted.
+func (s *SUT) Process() *binary.Process {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.proc
+}
+
+// Logs returns watcher of the output of the binary. It keeps receiving output when the binary is restarted.
+func (s *SUT) Logs() *logs.Watcher {
+	return s.logs
+}
+
+// Signal sends sig to the running binary, e.g. syscall.SIGHUP to reload its configuration.
+func (s *SUT) Signal(sig os.Signal) error {
+	return s.Process().Signal(sig)
+}
+
+// Stop stops the binary the same way as cleanup of the suite does. Stopped binary can be started again with Start.
+// Returns nil when the binary is already stopped.
+func (s *SUT) Stop(ctx context.Context) error {
+	p := s.Process()
+
+	done := make(chan error, 1)
+	go func() { done <- p.Stop() }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to stop %s: %w", s, err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to stop %s: %w", s, ctx.Err())
+	}
+}
+
+// Start starts stopped binary again with the same configuration and waits for its readiness check.
+// Output of the binary is appended to the same logs files.
+func (s *SUT) Start(ctx context.Context) error {
+	s.mtx.Lock()
+	if s.closed {
+		s.mtx.Unlock()
+		return fmt.Errorf("%s is already closed", s)
+	}
+	select {
+	case <-s.proc.Exited():
+	default:
+		s.mtx.Unlock()
+		return fmt.Errorf("%s is already running", s)
+	}
+	// Exit of the previous process was already reported by Stop, or by Attach when it exited by itself.
+	// Stop only releases its logs files and PID file here.
+	_ = s.proc.Stop()
+
+	opts := s.opts
+	opts.AppendLogs = true
+	if err := s.start(opts); err != nil {
+		s.mtx.Unlock()
+		return err
+	}
+	p := s.proc
+	s.mtx.Unlock()
+
+	if err := s.waitReady(ctx); err != nil {
+		// Error of the readiness check tells more than error of stopping the binary which isn't ready.
+		_ = p.Stop()
+		return err
+	}
+	return nil
+}
+
+// Restart stops the binary, starts it again and waits for its readiness check.
+func (s *SUT) Restart(ctx context.Context) error {
+	if err := s.Stop(ctx); err != nil {
+		return err
+	}
+	return s.Start(ctx)
+}
+
+// start runs new process of the binary, s.mtx must be held.
+func (s *SUT) start(opts binary.RunOptions) error {
+	mark := s.logs.Mark()
+	p, err := binary.Start(s.path, s.cfg.logsPath, opts)
+	if err != nil {
+		return fmt.Errorf("failed to run binary %q: %w", s.path, err)
+	}
+
+	s.proc = p
+	s.startMark = mark
+	if s.started != nil {
+		close(s.started)
+	}
+	s.started = make(chan struct{})
+	return nil
+}
+
+// current returns the current process and channel closed when it's replaced by a new one.
+func (s *SUT) current() (*binary.Process, <-chan struct{}) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.proc, s.started
+}
+
+// waitReady waits for readiness check of the current process.
+// It stops waiting as soon as the process exits and returns its exit status and tail of its logs.
+func (s *SUT) waitReady(ctx context.Context) error {
+	if s.readiness == nil {
+		return nil
+	}
+
+	p := s.Process()
+	ctx, cancel := context.WithCancel(s.withOutput(ctx))
+	defer cancel()
+	go func() {
+		select {
+		case <-p.Exited():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	err := s.suite.waitForAll(ctx, s.cfg, s.readiness)
+	if err == nil {
+		return nil
+	}
+
+	select {
+	case <-p.Exited():
+		exitErr := p.Err()
+		if exitErr == nil {
+			exitErr = fmt.Errorf("binary was stopped")
+		}
+		return fmt.Errorf("%s exited before it was ready: %w\n%s", s, exitErr, s.logsTail())
+	default:
+		return fmt.Errorf("failed to check readiness of %s: %w", s, err)
+	}
+}
+
+// withOutput binds output of the current process to checks run with the context, see waitfor.LogLine.
+func (s *SUT) withOutput(ctx context.Context) context.Context {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return waitfor.WithOutput(ctx, waitfor.Output{
+		Logs:   s.logs.Since(s.startMark),
+		Exited: s.proc.Exited(),
+		Err:    s.proc.Err,
+	})
+}
+
+// close stops the binary for good, it is registered as cleanup of the suite.
+func (s *SUT) close() error {
+	s.mtx.Lock()
+	s.closed = true
+	p := s.proc
+	s.mtx.Unlock()
+
+	return p.Stop()
+}
+
+func (s *SUT) isClosed() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.closed
+}
+
+func (s *SUT) String() string {
+	if s.name != "" {
+		return fmt.Sprintf("service %q", s.name)
+	}
+	return fmt.Sprintf("binary %q", s.path)
+}
@@ -0,0 +1,140 @@
+//go:build !windows
+// +build !windows
+
+package comptest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ingridhq/comptest/binary"
+	"github.com/ingridhq/comptest/waitfor"
+)
+
+func TestSUTRestart(t *testing.T) {
+	c := newSuite(t, WithRequireCleanShutdown())
+	s := runSUT(t, c)
+	first := s.Process()
+
+	// Binary stopped by the test doesn't fail it.
+	ft := &fakeT{}
+	c.Attach(ft)
+	if err := s.Restart(testContext(t)); err != nil {
+		t.Fatal(err)
+	}
+	ft.finish()
+	if out := ft.output(); ft.Failed() {
+		t.Errorf("restart failed the test:\n%s", out)
+	}
+
+	if s.Process() == first || s.Process().PID() == first.PID() {
+		t.Errorf("Restart() didn't start new process")
+	}
+	b, err := os.ReadFile(c.cfg.logsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if logs := string(b); strings.Count(logs, "ready\n") != 2 || !strings.Contains(logs, "terminated") {
+		t.Errorf("logs of both runs should be kept:\n%s", logs)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+}
+
+func TestSUTStopStart(t *testing.T) {
+	c := newSuite(t)
+	s := runSUT(t, c)
+	ctx := testContext(t)
+
+	if err := s.Start(ctx); err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Errorf("Start() = %v, want error for running binary", err)
+	}
+
+	if err := s.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Stop(ctx); err != nil {
+		t.Errorf("second Stop() = %v", err)
+	}
+	if err := s.Signal(syscall.SIGHUP); err == nil {
+		t.Errorf("Signal() succeeded for stopped binary")
+	}
+
+	if err := s.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Signal(syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitfor.Wait(ctx, logsContain(c.cfg.logsPath, "reloaded"), waitfor.ConstantRetryPolicy(10*time.Millisecond)); err != nil {
+		t.Errorf("binary didn't receive the signal: %v", err)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(ctx); err == nil || !strings.Contains(err.Error(), "already closed") {
+		t.Errorf("Start() = %v, want error for closed binary", err)
+	}
+}
+
+func TestSUTStartAfterExit(t *testing.T) {
+	c := newSuite(t)
+	s := runSUT(t, c, "-exit-after", "100ms")
+	<-s.Process().Exited()
+
+	// Binary which exited by itself can be started again, e.g. to test recovery after crash.
+	if err := s.Start(testContext(t)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-s.Process().Exited():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("binary didn't exit after it was started again")
+	}
+}
+
+func TestRunExitsBeforeReady(t *testing.T) {
+	c := newSuite(t)
+	never := checkerFunc{name: "[never]", fn: func(ctx context.Context) error { return errors.New("not ready") }}
+
+	start := time.Now()
+	_, err := c.TryRun(sutPath, never, WithArgs("-exit-after", "100ms", "-exit-code", "2"))
+
+	var exitErr *binary.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode != 2 {
+		t.Fatalf("TryRun() = %v, want *binary.ExitError with exit code 2", err)
+	}
+	for _, want := range []string{"exited before it was ready", "Last 20 lines of", "exiting"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error doesn't contain %q: %v", want, err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("TryRun() took %v, want to stop when the binary exits", elapsed)
+	}
+	if _, err := os.Stat(c.cfg.pidFile); !os.IsNotExist(err) {
+		t.Errorf("exited binary wasn't cleaned up: %v", err)
+	}
+}
+
+func TestStartExitsBeforeReady(t *testing.T) {
+	c := newSuite(t)
+	s := runSUT(t, c)
+	if err := s.Stop(testContext(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The binary exits right away when it can't listen on the address.
+	s.opts.Args = []string{"-listen", "invalid address"}
+	err := s.Start(testContext(t))
+	if err == nil || !strings.Contains(err.Error(), "exited before it was ready: ") || !strings.Contains(err.Error(), "exit status 2") {
+		t.Errorf("Start() = %v, want exit of the binary", err)
+	}
+}
@@ -0,0 +1,79 @@
+// Command sut is a binary run by tests of comptest. Flags control how it behaves on start and shutdown.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func main() {
+	ready := flag.String("ready", "ready", "line printed after start")
+	exitAfter := flag.Duration("exit-after", 0, "exit after the duration with -exit-code")
+	exitCode := flag.Int("exit-code", 0, "exit code used with -exit-after")
+	ignoreTerm := flag.Bool("ignore-term", false, "ignore SIGTERM")
+	termCode := flag.Int("term-code", 0, "exit code on SIGTERM")
+	child := flag.Bool("child", false, "start child process which ignores SIGTERM and print its PID")
+	listen := flag.String("listen", "", "address to accept TCP connections on")
+	flag.Parse()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGHUP)
+
+	if *child {
+		cmd := exec.Command(os.Args[0], "-ready", "child ready", "-ignore-term")
+		cmd.Stdout = os.Stdout
+		if err := cmd.Start(); err != nil {
+			fail(err)
+		}
+		fmt.Printf("child %d\n", cmd.Process.Pid)
+	}
+	if *listen != "" {
+		l, err := net.Listen("tcp", *listen)
+		if err != nil {
+			fail(err)
+		}
+		go func() {
+			for {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				conn.Close()
+			}
+		}()
+	}
+
+	fmt.Fprintln(os.Stderr, "stderr line")
+	fmt.Println(*ready)
+
+	var exit <-chan time.Time
+	if *exitAfter > 0 {
+		exit = time.After(*exitAfter)
+	}
+	for {
+		select {
+		case <-exit:
+			fmt.Println("exiting")
+			os.Exit(*exitCode)
+		case sig := <-signals:
+			switch {
+			case sig == syscall.SIGHUP:
+				fmt.Println("reloaded")
+			case !*ignoreTerm:
+				fmt.Println("terminated")
+				os.Exit(*termCode)
+			}
+		}
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(2)
+}
@@ -0,0 +1,176 @@
+package comptest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ingridhq/comptest/binary"
+)
+
+// Attach fails the test when any binary started by the suite exits unexpectedly while the test is running.
+// The failure contains exit status of the binary and the tail of its logs.
+//
+// Beginning and end of the test are marked in logs files of binaries. When the test fails,
+// logs written by binaries during the test are attached to it.
+//
+// Call it at the beginning of every test which uses binaries of the suite. Tests can't be attached
+// automatically, so without it unexpected exit is reported only by Close, which TestMain must check.
+func (c *comptest) Attach(t testing.TB) {
+	t.Helper()
+
+	suts := c.activeSUTs()
+	for _, s := range suts {
+		if err := s.Process().Err(); err != nil {
+			t.Fatalf("%v\n%s", err, s.logsTail())
+		}
+	}
+
+	segments := make([][]logSegment, len(suts))
+	for i, s := range suts {
+		segments[i] = s.beginSegment(t.Name())
+	}
+
+	var (
+		mtx      sync.Mutex
+		finished bool
+		reported bool
+	)
+	report := func(s *SUT, p *binary.Process) {
+		if err := p.Err(); !finished && !reported && err != nil {
+			reported = true
+			t.Errorf("%v\n%s", err, s.logsTail())
+		}
+	}
+
+	done := make(chan struct{})
+	for _, s := range suts {
+		s := s
+		go func() {
+			for {
+				p, started := s.current()
+				select {
+				case <-p.Exited():
+				case <-done:
+					return
+				}
+
+				mtx.Lock()
+				report(s, p)
+				mtx.Unlock()
+
+				// Binary stopped by the test can be started again.
+				select {
+				case <-started:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	t.Cleanup(func() {
+		close(done)
+
+		mtx.Lock()
+		defer mtx.Unlock()
+		for _, s := range suts {
+			report(s, s.Process())
+		}
+		finished = true
+
+		for i, s := range suts {
+			if logs := s.endSegment(t.Name(), t.Failed(), segments[i]); t.Failed() {
+				t.Log(logs)
+			}
+		}
+	})
+}
+
+// activeSUTs returns binaries which were not stopped by cleanup of the suite.
+func (c *comptest) activeSUTs() []*SUT {
+	c.processesMtx.Lock()
+	defer c.processesMtx.Unlock()
+
+	var suts []*SUT
+	for _, s := range c.processes {
+		if s.isClosed() && s.Process().Err() == nil {
+			continue
+		}
+		suts = append(suts, s)
+	}
+	return suts
+}
+
+// logsTail returns last lines of logs of the binary in a form attached to test failures.
+func (s *SUT) logsTail() string {
+	if s.cfg.logsTail <= 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	for _, path := range s.logsPaths() {
+		tail, err := binary.Tail(path, s.cfg.logsTail)
+		if err != nil {
+			tail = fmt.Sprintf("could not read logs: %v", err)
+		}
+		fmt.Fprintf(&sb, "Last %d lines of %s:\n%s\n", s.cfg.logsTail, path, tail)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// logSegment is a part of logs file written during a test.
+type logSegment struct {
+	path string
+	from int64
+}
+
+// beginSegment writes test start markers to logs files of the binary.
+func (s *SUT) beginSegment(name string) []logSegment {
+	var segments []logSegment
+	for _, path := range s.logsPaths() {
+		from, err := binary.AppendLine(path, fmt.Sprintf("=== comptest: BEGIN %s ===", name))
+		if err != nil {
+			continue
+		}
+		segments = append(segments, logSegment{path: path, from: from})
+	}
+	return segments
+}
+
+// endSegment writes test end markers to logs files of the binary and returns logs written during the test.
+func (s *SUT) endSegment(name string, failed bool, segments []logSegment) string {
+	result := "PASS"
+	if failed {
+		result = "FAIL"
+	}
+
+	var sb strings.Builder
+	for _, seg := range segments {
+		to, err := binary.Size(seg.path)
+		if err == nil {
+			_, err = binary.AppendLine(seg.path, fmt.Sprintf("=== comptest: END %s (%s) ===", name, result))
+		}
+
+		logs, readErr := binary.ReadRange(seg.path, seg.from, to)
+		switch {
+		case err != nil:
+			fmt.Fprintf(&sb, "Could not mark end of the test in %s: %v\n", seg.path, err)
+		case readErr != nil:
+			fmt.Fprintf(&sb, "Could not read logs of the test from %s: %v\n", seg.path, readErr)
+		default:
+			fmt.Fprintf(&sb, "Logs written to %s during the test:\n%s", seg.path, logs)
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func (s *SUT) logsPaths() []string {
+	p := s.Process()
+	paths := []string{p.LogsPath()}
+	if p.StderrPath() != p.LogsPath() {
+		paths = append(paths, p.StderrPath())
+	}
+	return paths
+}
@@ -0,0 +1,219 @@
+//go:build !windows
+// +build !windows
+
+package comptest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ingridhq/comptest/binary"
+	"github.com/ingridhq/comptest/waitfor"
+)
+
+// sutPath is path of the binary built from testdata/sut.
+var sutPath string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "comptest")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	sutPath = filepath.Join(dir, "sut")
+
+	code := 1
+	if out, err := exec.Command("go", "build", "-o", sutPath, "./testdata/sut").CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "couldn't build sut: %v\n%s", err, out)
+	} else {
+		code = m.Run()
+	}
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// logsContain returns check which succeeds when the file contains the line.
+func logsContain(path, line string) Checker {
+	return checkerFunc{name: fmt.Sprintf("[logs: %s]", line), fn: func(ctx context.Context) error {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, l := range strings.Split(string(b), "\n") {
+			if l == line {
+				return nil
+			}
+		}
+		return errors.New("line not found")
+	}}
+}
+
+// newSuite returns suite which keeps its files in temporary directory of the test and closes it after the test.
+func newSuite(t *testing.T, opts ...Option) *comptest {
+	dir := t.TempDir()
+	c := New(testContext(t), append([]Option{
+		WithLogsPath(filepath.Join(dir, "sut.log")),
+		WithPIDFile(filepath.Join(dir, "sut.pid")),
+		WithRetryPolicy(waitfor.ConstantRetryPolicy(10 * time.Millisecond)),
+		WithReporter(NopReporter()),
+		WithShutdownTimeout(5 * time.Second),
+	}, opts...)...)
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+// runSUT runs the sut with args in the suite. Its readiness check waits for output of the current process,
+// so the binary is ready also after it was restarted.
+func runSUT(t *testing.T, c *comptest, args ...string) *SUT {
+	t.Helper()
+	if _, err := c.TryRun(sutPath, waitfor.LogLine("^ready$"), WithArgs(args...)); err != nil {
+		t.Fatal(err)
+	}
+	return c.SUT("")
+}
+
+// fakeT records failures and logs of a test instead of failing it.
+type fakeT struct {
+	testing.TB
+
+	mtx      sync.Mutex
+	errors   []string
+	logs     []string
+	cleanups []func()
+}
+
+func (t *fakeT) Helper()      {}
+func (t *fakeT) Name() string { return "TestFake" }
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+// Fatalf doesn't stop the test, it only records the failure.
+func (t *fakeT) Fatalf(format string, args ...interface{}) {
+	t.Errorf(format, args...)
+}
+
+func (t *fakeT) Failed() bool {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return len(t.errors) > 0
+}
+
+func (t *fakeT) Log(args ...interface{}) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.logs = append(t.logs, fmt.Sprint(args...))
+}
+
+func (t *fakeT) Cleanup(fn func()) {
+	t.cleanups = append(t.cleanups, fn)
+}
+
+// finish runs cleanups of the test.
+func (t *fakeT) finish() {
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+}
+
+func (t *fakeT) output() string {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return strings.Join(append(append([]string{}, t.errors...), t.logs...), "\n")
+}
+
+func TestAttachReportsExit(t *testing.T) {
+	c := newSuite(t)
+	s := runSUT(t, c, "-exit-after", "300ms", "-exit-code", "3")
+
+	ft := &fakeT{}
+	c.Attach(ft)
+	<-s.Process().Exited()
+	for deadline := time.Now().Add(5 * time.Second); !ft.Failed(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("exit of the binary wasn't reported to the test")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	ft.finish()
+
+	out := ft.output()
+	for _, want := range []string{"exited before it was closed by tests: exit status 3", "Last 20 lines of", "exiting", "Logs written to"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("failure of the test doesn't contain %q:\n%s", want, out)
+		}
+	}
+
+	var exitErr *binary.ExitError
+	if err := c.Close(); !errors.As(err, &exitErr) || exitErr.ExitCode != 3 {
+		t.Errorf("Close() = %v, want *binary.ExitError with exit code 3", err)
+	}
+}
+
+func TestAttachAfterExit(t *testing.T) {
+	c := newSuite(t, WithLogsTail(0))
+	s := runSUT(t, c, "-exit-after", "100ms", "-exit-code", "1")
+	<-s.Process().Exited()
+
+	ft := &fakeT{}
+	c.Attach(ft)
+	ft.finish()
+
+	if out := ft.output(); !strings.Contains(out, "exit status 1") || strings.Contains(out, "Last") {
+		t.Errorf("test failure = %q, want exit status without logs", out)
+	}
+}
+
+func TestAttachLogsSegments(t *testing.T) {
+	c := newSuite(t)
+	s := runSUT(t, c)
+
+	passed := &fakeT{}
+	c.Attach(passed)
+	passed.finish()
+
+	failed := &fakeT{}
+	c.Attach(failed)
+	if err := s.Signal(syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitfor.Wait(testContext(t), logsContain(c.cfg.logsPath, "reloaded"), waitfor.ConstantRetryPolicy(10*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	failed.Errorf("failure")
+	failed.finish()
+
+	if len(passed.logs) != 0 {
+		t.Errorf("logs were attached to passed test: %q", passed.logs)
+	}
+	if out := failed.output(); !strings.Contains(out, "reloaded") || strings.Contains(out, "ready") {
+		t.Errorf("logs attached to failed test should contain only its logs:\n%s", out)
+	}
+
+	b, err := os.ReadFile(c.cfg.logsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"=== comptest: BEGIN TestFake ===",
+		"=== comptest: END TestFake (PASS) ===",
+		"=== comptest: BEGIN TestFake ===",
+		"reloaded",
+		"=== comptest: END TestFake (FAIL) ===",
+	}
+	if logs := string(b); !strings.Contains(logs, strings.Join(want, "\n")) {
+		t.Errorf("logs = %q, want markers of the tests", logs)
+	}
+}
@@ -0,0 +1,207 @@
+package waitfor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ingridhq/comptest/internal/multierr"
+)
+
+// Checker is implemented by every health check.
+// Check is called repeatedly until it returns nil.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// All succeeds when all checks succeed. Checks are run concurrently.
+func All(checks ...Checker) allHealthCheck {
+	return allHealthCheck{checks: checks}
+}
+
+type allHealthCheck struct {
+	checks []Checker
+}
+
+func (c allHealthCheck) String() string {
+	return fmt.Sprintf("[All: %s]", joinChecks(c.checks))
+}
+
+func (c allHealthCheck) Check(ctx context.Context) error {
+	// Permanent failure of one check decides the result, others are not waited for.
+	errs, stopped := checkConcurrently(ctx, c.checks, isPermanent)
+	if stopped {
+		return Permanent(errs)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Any succeeds when at least one of the checks succeeds. Checks are run concurrently.
+func Any(checks ...Checker) anyHealthCheck {
+	return anyHealthCheck{checks: checks}
+}
+
+type anyHealthCheck struct {
+	checks []Checker
+}
+
+func (c anyHealthCheck) String() string {
+	return fmt.Sprintf("[Any: %s]", joinChecks(c.checks))
+}
+
+func (c anyHealthCheck) Check(ctx context.Context) error {
+	if len(c.checks) == 0 {
+		return fmt.Errorf("no checks to run")
+	}
+
+	// The first succeeded check decides the result, others are not waited for.
+	errs, stopped := checkConcurrently(ctx, c.checks, func(err error) bool { return err == nil })
+	if stopped {
+		return nil
+	}
+	for _, err := range errs {
+		if !isPermanent(err) {
+			return errs
+		}
+	}
+	return Permanent(errs)
+}
+
+// Sequence succeeds when all checks succeed one after another.
+// Checks which already succeeded are not run again on the next attempts.
+func Sequence(checks ...Checker) *sequenceHealthCheck {
+	return &sequenceHealthCheck{checks: checks}
+}
+
+type sequenceHealthCheck struct {
+	checks []Checker
+
+	mtx    sync.Mutex
+	passed int
+}
+
+func (c *sequenceHealthCheck) String() string {
+	return fmt.Sprintf("[Sequence: %s]", joinChecks(c.checks))
+}
+
+func (c *sequenceHealthCheck) Check(ctx context.Context) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for c.passed < len(c.checks) {
+		check := c.checks[c.passed]
+		if err := check.Check(ctx); err != nil {
+			return fmt.Errorf("check %v failed: %w", check, err)
+		}
+		c.passed++
+	}
+
+	// Start from the beginning when the sequence is reused.
+	c.passed = 0
+	return nil
+}
+
+// WithTimeout limits time of every single attempt of the check.
+func WithTimeout(check Checker, timeout time.Duration) timeoutHealthCheck {
+	return timeoutHealthCheck{check: check, timeout: timeout}
+}
+
+type timeoutHealthCheck struct {
+	check   Checker
+	timeout time.Duration
+}
+
+func (c timeoutHealthCheck) String() string {
+	return fmt.Sprintf("%v", c.check)
+}
+
+func (c timeoutHealthCheck) Check(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	return c.check.Check(ctx)
+}
+
+// WithName overrides name of the check used in logs and errors.
+func WithName(check Checker, name string) namedHealthCheck {
+	return namedHealthCheck{check: check, name: name}
+}
+
+type namedHealthCheck struct {
+	check Checker
+	name  string
+}
+
+func (c namedHealthCheck) String() string {
+	return fmt.Sprintf("[%s]", c.name)
+}
+
+func (c namedHealthCheck) Check(ctx context.Context) error {
+	return c.check.Check(ctx)
+}
+
+// Not succeeds when the check fails, e.g. to wait until old instance of a service is gone.
+func Not(check Checker) notHealthCheck {
+	return notHealthCheck{check: check}
+}
+
+type notHealthCheck struct {
+	check Checker
+}
+
+func (c notHealthCheck) String() string {
+	return fmt.Sprintf("[Not: %v]", c.check)
+}
+
+func (c notHealthCheck) Check(ctx context.Context) error {
+	if err := c.check.Check(ctx); err == nil {
+		return fmt.Errorf("check %v succeeded", c.check)
+	}
+
+	// Failure caused by cancelled context doesn't tell anything about the check.
+	return ctx.Err()
+}
+
+// checkConcurrently runs the checks and collects their errors. It stops as soon as stop accepts
+// result of a check, then the remaining checks are cancelled and their results are ignored.
+func checkConcurrently(ctx context.Context, checks []Checker, stop func(err error) bool) (errs multierr.Errors, stopped bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Buffered, so checks which are not waited for don't block.
+	results := make(chan error, len(checks))
+	for _, check := range checks {
+		check := check
+		go func() {
+			if err := check.Check(ctx); err != nil {
+				results <- fmt.Errorf("check %v failed: %w", check, err)
+				return
+			}
+			results <- nil
+		}()
+	}
+
+	for range checks {
+		err := <-results
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if stop(err) {
+			return errs, true
+		}
+	}
+	return errs, false
+}
+
+func joinChecks(checks []Checker) string {
+	names := make([]string, 0, len(checks))
+	for _, c := range checks {
+		names = append(names, fmt.Sprintf("%v", c))
+	}
+	return strings.Join(names, ", ")
+}
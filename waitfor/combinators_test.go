@@ -0,0 +1,162 @@
+package waitfor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// checkFunc is a check which runs the function.
+type checkFunc func(ctx context.Context) error
+
+func (f checkFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+var (
+	okCheck   = checkFunc(func(ctx context.Context) error { return nil })
+	failCheck = checkFunc(func(ctx context.Context) error { return errors.New("not ready") })
+	// hangCheck blocks until the context is done, like dial to a blackholed address.
+	hangCheck = checkFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+)
+
+func TestAll(t *testing.T) {
+	if err := All(okCheck, okCheck).Check(context.Background()); err != nil {
+		t.Errorf("All(ok, ok) = %v", err)
+	}
+	if err := All().Check(context.Background()); err != nil {
+		t.Errorf("All() = %v", err)
+	}
+
+	err := All(okCheck, failCheck).Check(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "not ready") || isPermanent(err) {
+		t.Errorf("All(ok, fail) = %v, want temporary error", err)
+	}
+}
+
+func TestAllStopsOnPermanentError(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	permanent := checkFunc(func(ctx context.Context) error { return Permanent(errors.New("bad config")) })
+	start := time.Now()
+	err := All(hangCheck, permanent).Check(ctx)
+	if !isPermanent(err) || !strings.Contains(err.Error(), "bad config") {
+		t.Errorf("All(hang, permanent) = %v, want permanent error", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("All(hang, permanent) waited %v for the hanging check", elapsed)
+	}
+}
+
+func TestAny(t *testing.T) {
+	if err := Any(failCheck, okCheck).Check(context.Background()); err != nil {
+		t.Errorf("Any(fail, ok) = %v", err)
+	}
+	if err := Any().Check(context.Background()); err == nil {
+		t.Errorf("Any() succeeded")
+	}
+
+	err := Any(failCheck, failCheck).Check(context.Background())
+	if err == nil || isPermanent(err) {
+		t.Errorf("Any(fail, fail) = %v, want temporary error", err)
+	}
+
+	permanent := checkFunc(func(ctx context.Context) error { return Permanent(errors.New("bad config")) })
+	if err := Any(permanent, permanent).Check(context.Background()); !isPermanent(err) {
+		t.Errorf("Any(permanent, permanent) = %v, want permanent error", err)
+	}
+	if err := Any(permanent, failCheck).Check(context.Background()); isPermanent(err) {
+		t.Errorf("Any(permanent, fail) = %v, want temporary error", err)
+	}
+}
+
+func TestAnyDoesNotWaitForOthers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cancelled := make(chan struct{})
+	hang := checkFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	})
+
+	start := time.Now()
+	if err := Any(okCheck, hang).Check(ctx); err != nil {
+		t.Fatalf("Any(ok, hang) = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Any(ok, hang) waited %v for the hanging check", elapsed)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Errorf("hanging check wasn't cancelled")
+	}
+}
+
+func TestSequence(t *testing.T) {
+	var first, second int32
+	ready := int32(0)
+	seq := Sequence(
+		checkFunc(func(ctx context.Context) error { atomic.AddInt32(&first, 1); return nil }),
+		checkFunc(func(ctx context.Context) error {
+			atomic.AddInt32(&second, 1)
+			if atomic.LoadInt32(&ready) == 0 {
+				return errors.New("not ready")
+			}
+			return nil
+		}),
+	)
+
+	if err := seq.Check(context.Background()); err == nil {
+		t.Fatalf("Sequence succeeded before the second check")
+	}
+	atomic.StoreInt32(&ready, 1)
+	if err := seq.Check(context.Background()); err != nil {
+		t.Fatalf("Sequence = %v", err)
+	}
+	if first != 1 || second != 2 {
+		t.Errorf("checks were run %d and %d times, want 1 and 2", first, second)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	err := WithTimeout(hangCheck, 10*time.Millisecond).Check(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WithTimeout(hang) = %v, want deadline exceeded", err)
+	}
+}
+
+func TestNot(t *testing.T) {
+	if err := Not(failCheck).Check(context.Background()); err != nil {
+		t.Errorf("Not(fail) = %v", err)
+	}
+	if err := Not(okCheck).Check(context.Background()); err == nil {
+		t.Errorf("Not(ok) succeeded")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Not(hangCheck).Check(ctx); err == nil {
+		t.Errorf("Not(hang) succeeded with cancelled context")
+	}
+}
+
+func TestNames(t *testing.T) {
+	db, a, b := WithName(okCheck, "db"), WithName(okCheck, "a"), WithName(okCheck, "b")
+	if got := WithTimeout(WithName(All(db, a), "deps"), time.Second).String(); got != "[deps]" {
+		t.Errorf("String() = %s, want [deps]", got)
+	}
+	if got := All(db, Any(a, b)).String(); got != "[All: [db], [Any: [a], [b]]]" {
+		t.Errorf("String() = %s", got)
+	}
+}
@@ -0,0 +1,31 @@
+package waitfor
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// DNS succeeds when the host resolves to at least one address.
+func DNS(host string) dnsHealthCheck {
+	return dnsHealthCheck{host: host}
+}
+
+type dnsHealthCheck struct {
+	host string
+}
+
+func (c dnsHealthCheck) String() string {
+	return fmt.Sprintf("[DNSCheck: %s]", c.host)
+}
+
+func (c dnsHealthCheck) Check(ctx context.Context) error {
+	addrs, err := net.DefaultResolver.LookupHost(ctx, c.host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve: %w", err)
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("no addresses found")
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package waitfor
+
+import (
+	"testing"
+)
+
+func TestDNS(t *testing.T) {
+	ctx := testContext(t)
+
+	if err := DNS("localhost").Check(ctx); err != nil {
+		t.Errorf("Check() = %v", err)
+	}
+	// The .invalid domain never resolves, see RFC 2606.
+	if err := DNS("comptest.invalid").Check(ctx); err == nil {
+		t.Errorf("Check() succeeded for invalid domain")
+	}
+}
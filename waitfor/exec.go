@@ -0,0 +1,46 @@
+package waitfor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/ingridhq/comptest/ports"
+)
+
+// Exec succeeds when the command exits with code 0, e.g. Exec("pg_isready", "-h", "localhost").
+// Arguments can contain placeholders of reserved ports, see ports.ResolveReserved.
+func Exec(cmd string, args ...string) execHealthCheck {
+	return execHealthCheck{cmd: cmd, args: args}
+}
+
+type execHealthCheck struct {
+	cmd  string
+	args []string
+}
+
+func (c execHealthCheck) String() string {
+	return fmt.Sprintf("[ExecCheck: %s]", strings.Join(append([]string{c.cmd}, c.args...), " "))
+}
+
+func (c execHealthCheck) Check(ctx context.Context) error {
+	args := make([]string, 0, len(c.args))
+	for _, arg := range c.args {
+		resolved, err := ports.ResolveReserved(arg)
+		if err != nil {
+			return Permanent(err)
+		}
+		args = append(args, resolved)
+	}
+
+	out, err := exec.CommandContext(ctx, c.cmd, args...).CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	if out = bytes.TrimSpace(out); len(out) > 0 {
+		return fmt.Errorf("command failed: %w: %s", err, out)
+	}
+	return fmt.Errorf("command failed: %w", err)
+}
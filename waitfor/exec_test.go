@@ -0,0 +1,42 @@
+package waitfor
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ingridhq/comptest/ports"
+)
+
+func TestExec(t *testing.T) {
+	ctx := testContext(t)
+
+	if err := Exec("go", "version").Check(ctx); err != nil {
+		t.Errorf("Check() = %v", err)
+	}
+	if err := Exec("go", "no-such-command").Check(ctx); err == nil || !strings.Contains(err.Error(), "no-such-command") {
+		t.Errorf("Check() = %v, want error with output of the command", err)
+	}
+	if err := Exec("no-such-binary-comptest").Check(ctx); err == nil {
+		t.Errorf("Check() succeeded for missing binary")
+	}
+}
+
+func TestExecPorts(t *testing.T) {
+	port := ports.MustReserve("waitfor-exec")
+	ctx := testContext(t)
+
+	// The test binary runs no tests, it fails only when the count isn't a number.
+	if err := Exec(os.Args[0], "-test.run=^$", "-test.count={port:waitfor-exec}").Check(ctx); err != nil {
+		t.Errorf("Check() = %v, want port %d resolved", err, port)
+	}
+	if err := Exec(os.Args[0], "-test.run=^$", "-test.count=port").Check(ctx); err == nil {
+		t.Errorf("Check() succeeded with invalid flag")
+	}
+	if err := Exec("go", "{port:waitfor-exec-unknown}").Check(ctx); err == nil || !isPermanent(err) {
+		t.Errorf("Check() = %v, want permanent error of unknown port", err)
+	}
+	if got := Exec("pg_isready", "-h", "localhost").String(); got != "[ExecCheck: pg_isready -h localhost]" {
+		t.Errorf("String() = %q", got)
+	}
+}
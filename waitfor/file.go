@@ -0,0 +1,55 @@
+package waitfor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+)
+
+// File succeeds when the file exists, e.g. config file generated by a sidecar.
+func File(path string) fileHealthCheck {
+	return fileHealthCheck{path: path}
+}
+
+type fileHealthCheck struct {
+	path string
+}
+
+func (c fileHealthCheck) String() string {
+	return fmt.Sprintf("[FileCheck: %s]", c.path)
+}
+
+func (c fileHealthCheck) Check(ctx context.Context) error {
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", c.path)
+	}
+	return nil
+}
+
+// UnixSocket succeeds when connection to the unix socket is established.
+func UnixSocket(path string) unixSocketHealthCheck {
+	return unixSocketHealthCheck{path: path}
+}
+
+type unixSocketHealthCheck struct {
+	path string
+}
+
+func (c unixSocketHealthCheck) String() string {
+	return fmt.Sprintf("[UnixSocketCheck: %s]", c.path)
+}
+
+func (c unixSocketHealthCheck) Check(ctx context.Context) error {
+	d := net.Dialer{}
+	conn, err := d.DialContext(ctx, "unix", c.path)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	conn.Close()
+	return nil
+}
@@ -0,0 +1,62 @@
+package waitfor
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	ctx := testContext(t)
+
+	if err := File(path).Check(ctx); err == nil {
+		t.Errorf("Check() succeeded for missing file")
+	}
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := File(path).Check(ctx); err != nil {
+		t.Errorf("Check() = %v", err)
+	}
+	if err := File(dir).Check(ctx); err == nil || !strings.Contains(err.Error(), "is a directory") {
+		t.Errorf("Check() = %v, want error for directory", err)
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	// Paths of unix sockets are limited to about 100 characters, temporary directory of the test could be longer.
+	dir, err := os.MkdirTemp("", "sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.sock")
+	ctx := testContext(t)
+
+	if err := UnixSocket(path).Check(ctx); err == nil {
+		t.Errorf("Check() succeeded without listener")
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets are not supported: %v", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	if err := UnixSocket(path).Check(ctx); err != nil {
+		t.Errorf("Check() = %v", err)
+	}
+}
@@ -0,0 +1,24 @@
+package waitfor
+
+import (
+	"context"
+	"fmt"
+)
+
+// Func wraps function as a check. Name is used in logs and errors.
+func Func(name string, fn func(ctx context.Context) error) funcHealthCheck {
+	return funcHealthCheck{name: name, fn: fn}
+}
+
+type funcHealthCheck struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+func (c funcHealthCheck) String() string {
+	return fmt.Sprintf("[%s]", c.name)
+}
+
+func (c funcHealthCheck) Check(ctx context.Context) error {
+	return c.fn(ctx)
+}
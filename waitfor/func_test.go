@@ -0,0 +1,26 @@
+package waitfor
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFunc(t *testing.T) {
+	var got context.Context
+	check := Func("orders", func(ctx context.Context) error {
+		got = ctx
+		return errors.New("no orders")
+	})
+
+	ctx := testContext(t)
+	if err := check.Check(ctx); err == nil || err.Error() != "no orders" {
+		t.Errorf("Check() = %v, want error of the function", err)
+	}
+	if got != ctx {
+		t.Errorf("function didn't receive context of the check")
+	}
+	if check.String() != "[orders]" {
+		t.Errorf("String() = %q", check.String())
+	}
+}
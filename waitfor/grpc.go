@@ -0,0 +1,99 @@
+package waitfor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ingridhq/comptest/ports"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+)
+
+// GRPC succeeds when GRPC server at addr reports the service as SERVING with grpc.health.v1.Health/Check.
+// Empty service checks health of the whole server. When the server doesn't implement health service,
+// the service must be listed by server reflection instead.
+// Address accepts the same options as comptest.CreateGRPCConn, e.g. "localhost:9090?insecure=true".
+// Without insecure option connection uses TLS.
+func GRPC(addr, service string) grpcHealthCheck {
+	return grpcHealthCheck{addr: addr, service: service}
+}
+
+type grpcHealthCheck struct {
+	addr    string
+	service string
+}
+
+func (c grpcHealthCheck) String() string {
+	if c.service != "" {
+		return fmt.Sprintf("[GRPCCheck: %s %s]", c.addr, c.service)
+	}
+	return fmt.Sprintf("[GRPCCheck: %s]", c.addr)
+}
+
+func (c grpcHealthCheck) Check(ctx context.Context) error {
+	addr, err := ports.ResolveReserved(cleanupAddress(c.addr))
+	if err != nil {
+		return Permanent(err)
+	}
+
+	conn, err := grpc.DialContext(ctx, addr, grpcDialOptions(c.addr)...)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	resp, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: c.service})
+	if status.Code(err) == codes.Unimplemented {
+		return c.checkReflection(ctx, conn)
+	}
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+
+	if resp.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		return fmt.Errorf("unexpected status: %v", resp.Status)
+	}
+	return nil
+}
+
+// checkReflection checks that the service is listed by server reflection.
+func (c grpcHealthCheck) checkReflection(ctx context.Context, conn *grpc.ClientConn) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return fmt.Errorf("health service is not implemented and reflection failed: %w", err)
+	}
+	if err := stream.Send(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_ListServices{},
+	}); err != nil {
+		return fmt.Errorf("health service is not implemented and reflection failed: %w", err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return fmt.Errorf("health service is not implemented and reflection failed: %w", err)
+	}
+
+	if c.service == "" {
+		return nil
+	}
+	for _, svc := range resp.GetListServicesResponse().GetService() {
+		if svc.Name == c.service {
+			return nil
+		}
+	}
+	return fmt.Errorf("service %q is not listed by reflection", c.service)
+}
+
+func grpcDialOptions(addr string) []grpc.DialOption {
+	if strings.Contains(addr, "insecure=true") {
+		return []grpc.DialOption{grpc.WithInsecure()}
+	}
+	return []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(nil))}
+}
@@ -0,0 +1,98 @@
+package waitfor
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// serveGRPC starts GRPC server with services registered by register and returns its insecure address.
+func serveGRPC(t *testing.T, register func(s *grpc.Server)) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	register(s)
+	// Serve returns error when the server is stopped by cleanup of the test.
+	go func() { _ = s.Serve(l) }()
+	t.Cleanup(s.Stop)
+	return l.Addr().String() + "?insecure=true"
+}
+
+func TestGRPCHealth(t *testing.T) {
+	hs := health.NewServer()
+	hs.SetServingStatus("orders.Orders", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	addr := serveGRPC(t, func(s *grpc.Server) { grpc_health_v1.RegisterHealthServer(s, hs) })
+	ctx := testContext(t)
+
+	if err := GRPC(addr, "").Check(ctx); err != nil {
+		t.Errorf("Check() of the server = %v", err)
+	}
+	if err := GRPC(addr, "orders.Orders").Check(ctx); err == nil || !strings.Contains(err.Error(), "NOT_SERVING") {
+		t.Errorf("Check() = %v, want NOT_SERVING status", err)
+	}
+	if err := GRPC(addr, "users.Users").Check(ctx); err == nil {
+		t.Errorf("Check() of unknown service succeeded")
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		hs.SetServingStatus("orders.Orders", grpc_health_v1.HealthCheckResponse_SERVING)
+	}()
+	if err := Wait(ctx, GRPC(addr, "orders.Orders"), ConstantRetryPolicy(10*time.Millisecond)); err != nil {
+		t.Errorf("Wait() = %v", err)
+	}
+}
+
+func TestGRPCReflection(t *testing.T) {
+	// Health service is registered only to be listed by reflection, its Check isn't implemented.
+	addr := serveGRPC(t, func(s *grpc.Server) {
+		grpc_health_v1.RegisterHealthServer(s, grpc_health_v1.UnimplementedHealthServer{})
+		reflection.Register(s)
+	})
+	ctx := testContext(t)
+
+	if err := GRPC(addr, "").Check(ctx); err != nil {
+		t.Errorf("Check() of the server = %v", err)
+	}
+	if err := GRPC(addr, "grpc.health.v1.Health").Check(ctx); err != nil {
+		t.Errorf("Check() of listed service = %v", err)
+	}
+	if err := GRPC(addr, "orders.Orders").Check(ctx); err == nil || !strings.Contains(err.Error(), "not listed by reflection") {
+		t.Errorf("Check() = %v, want service not listed", err)
+	}
+}
+
+func TestGRPCWithoutHealthAndReflection(t *testing.T) {
+	addr := serveGRPC(t, func(s *grpc.Server) {})
+
+	if err := GRPC(addr, "").Check(testContext(t)); err == nil || !strings.Contains(err.Error(), "reflection failed") {
+		t.Errorf("Check() = %v, want error of reflection", err)
+	}
+}
+
+func TestGRPCNotListening(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	policy := ConstantRetryPolicy(10 * time.Millisecond)
+	policy.MaxAttempts = 3
+	if err := Wait(testContext(t), GRPC(addr+"?insecure=true", ""), policy); err == nil {
+		t.Errorf("Wait() succeeded without server")
+	}
+	if got := GRPC(addr, "orders.Orders").String(); got != "[GRPCCheck: "+addr+" orders.Orders]" {
+		t.Errorf("String() = %q", got)
+	}
+}
@@ -1,38 +1,291 @@
 package waitfor
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ingridhq/comptest/internal/multierr"
+	"github.com/ingridhq/comptest/ports"
 )
 
-func HTTP(addr string) httpHealthCheck {
-	return httpHealthCheck{addr: addr}
+// maxBodySize limits size of the response body read by HTTP check.
+const maxBodySize = 1 << 20
+
+// HTTP succeeds when request to addr returns expected response, by default GET request and 200 status code.
+func HTTP(addr string, opts ...HTTPOption) httpHealthCheck {
+	c := httpHealthCheck{addr: addr, method: http.MethodGet, header: http.Header{}}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if len(c.statuses) == 0 {
+		c.statuses = []statusRange{{min: http.StatusOK, max: http.StatusOK}}
+	}
+	c.client = c.newClient()
+	return c
+}
+
+// HTTPOption configures HTTP check.
+type HTTPOption func(c *httpHealthCheck)
+
+// HTTPMethod sets method of the request, GET by default.
+func HTTPMethod(method string) HTTPOption {
+	return func(c *httpHealthCheck) {
+		c.method = method
+	}
+}
+
+// HTTPHeader adds header to the request, e.g. "Authorization".
+func HTTPHeader(key, value string) HTTPOption {
+	return func(c *httpHealthCheck) {
+		c.header.Add(key, value)
+	}
+}
+
+// HTTPBody sets body of the request.
+func HTTPBody(body []byte) HTTPOption {
+	return func(c *httpHealthCheck) {
+		c.body = body
+	}
+}
+
+// HTTPStatus sets accepted status codes of the response. It can be combined with HTTPStatusRange.
+func HTTPStatus(codes ...int) HTTPOption {
+	return func(c *httpHealthCheck) {
+		for _, code := range codes {
+			c.statuses = append(c.statuses, statusRange{min: code, max: code})
+		}
+	}
+}
+
+// HTTPStatusRange accepts status codes of the response from min to max inclusive, e.g. 200-299.
+func HTTPStatusRange(min, max int) HTTPOption {
+	return func(c *httpHealthCheck) {
+		c.statuses = append(c.statuses, statusRange{min: min, max: max})
+	}
+}
+
+// HTTPBodyContains requires body of the response to contain the substring.
+func HTTPBodyContains(substr string) HTTPOption {
+	return func(c *httpHealthCheck) {
+		c.matchers = append(c.matchers, func(body []byte) error {
+			if !bytes.Contains(body, []byte(substr)) {
+				return fmt.Errorf("body doesn't contain %q", substr)
+			}
+			return nil
+		})
+	}
+}
+
+// HTTPBodyRegexp requires body of the response to match the regular expression.
+func HTTPBodyRegexp(expr string) HTTPOption {
+	return func(c *httpHealthCheck) {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			c.errs = append(c.errs, fmt.Errorf("invalid body regexp: %w", err))
+			return
+		}
+		c.matchers = append(c.matchers, func(body []byte) error {
+			if !re.Match(body) {
+				return fmt.Errorf("body doesn't match %q", expr)
+			}
+			return nil
+		})
+	}
+}
+
+// HTTPBodyJSON requires JSON body of the response to have expected value under the path.
+// Path consists of object keys and array indexes separated with dots, e.g. "checks.0.status".
+// Values are compared by their string representation, so 1 matches 1.0.
+func HTTPBodyJSON(path string, expected interface{}) HTTPOption {
+	return func(c *httpHealthCheck) {
+		c.matchers = append(c.matchers, func(body []byte) error {
+			var doc interface{}
+			if err := json.Unmarshal(body, &doc); err != nil {
+				return fmt.Errorf("body is not valid JSON: %w", err)
+			}
+			value, err := jsonPath(doc, path)
+			if err != nil {
+				return err
+			}
+			if fmt.Sprint(value) != fmt.Sprint(expected) {
+				return fmt.Errorf("unexpected value of %q: %v", path, value)
+			}
+			return nil
+		})
+	}
+}
+
+// HTTPTLSConfig sets TLS configuration of the client.
+func HTTPTLSConfig(cfg *tls.Config) HTTPOption {
+	return func(c *httpHealthCheck) {
+		c.tls = cfg.Clone()
+	}
+}
+
+// HTTPInsecure disables verification of the server certificate.
+func HTTPInsecure() HTTPOption {
+	return func(c *httpHealthCheck) {
+		c.tlsConfig().InsecureSkipVerify = true
+	}
+}
+
+// HTTPRootCA makes the client trust certificates signed by CA from the PEM file.
+func HTTPRootCA(pemPath string) HTTPOption {
+	return func(c *httpHealthCheck) {
+		pem, err := os.ReadFile(pemPath)
+		if err != nil {
+			c.errs = append(c.errs, fmt.Errorf("couldn't read CA: %w", err))
+			return
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			c.errs = append(c.errs, fmt.Errorf("no certificates found in %q", pemPath))
+			return
+		}
+		c.tlsConfig().RootCAs = pool
+	}
+}
+
+// HTTPUnixSocket sends requests through unix socket. Host of the address is ignored,
+// e.g. HTTP("http://localhost/health", HTTPUnixSocket("/tmp/service.sock")).
+func HTTPUnixSocket(path string) HTTPOption {
+	return func(c *httpHealthCheck) {
+		c.socket = path
+	}
+}
+
+type statusRange struct {
+	min, max int
 }
 
 type httpHealthCheck struct {
-	addr string
+	addr     string
+	method   string
+	header   http.Header
+	body     []byte
+	statuses []statusRange
+	matchers []func(body []byte) error
+	tls      *tls.Config
+	socket   string
+	client   *http.Client
+	// errs are errors of options, they are returned by Check.
+	errs multierr.Errors
 }
 
 func (c httpHealthCheck) String() string {
+	if c.method != http.MethodGet {
+		return fmt.Sprintf("[HTTPCheck: %s %s]", c.method, c.addr)
+	}
 	return fmt.Sprintf("[HTTPCheck: %s]", c.addr)
 }
 
 func (c httpHealthCheck) Check(ctx context.Context) error {
-	req, err := http.NewRequest("GET", c.addr, nil)
+	if len(c.errs) > 0 {
+		return Permanent(c.errs)
+	}
+
+	addr, err := ports.ResolveReserved(c.addr)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	req, err := http.NewRequest(c.method, addr, bytes.NewReader(c.body))
 	if err != nil {
 		return fmt.Errorf("failed to prepare the request: %w", err)
 	}
 	req = req.WithContext(ctx)
+	for key, values := range c.header {
+		req.Header[key] = values
+	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to run the request: %w", err)
 	}
+	defer resp.Body.Close()
+
+	if !c.statusAccepted(resp.StatusCode) {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
 
-	if resp.StatusCode == http.StatusOK {
+	if len(c.matchers) == 0 {
 		return nil
 	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return fmt.Errorf("failed to read the response: %w", err)
+	}
+	for _, match := range c.matchers {
+		if err := match(body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+func (c httpHealthCheck) statusAccepted(code int) bool {
+	for _, r := range c.statuses {
+		if code >= r.min && code <= r.max {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *httpHealthCheck) tlsConfig() *tls.Config {
+	if c.tls == nil {
+		c.tls = &tls.Config{}
+	}
+	return c.tls
+}
+
+func (c httpHealthCheck) newClient() *http.Client {
+	if c.tls == nil && c.socket == "" {
+		return http.DefaultClient
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = c.tls
+	if c.socket != "" {
+		socket := c.socket
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		}
+	}
+	return &http.Client{Transport: transport}
+}
+
+func jsonPath(doc interface{}, path string) (interface{}, error) {
+	value := doc
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			field, ok := v[key]
+			if !ok {
+				return nil, fmt.Errorf("no %q in JSON body", path)
+			}
+			value = field
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("no %q in JSON body", path)
+			}
+			value = v[i]
+		default:
+			return nil, fmt.Errorf("no %q in JSON body", path)
+		}
+	}
+	return value, nil
 }
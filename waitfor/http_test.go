@@ -0,0 +1,41 @@
+package waitfor
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestJSONPath(t *testing.T) {
+	var doc interface{}
+	body := `{"status":"up","checks":[{"name":"db","status":"down"}],"version":{"major":2}}`
+	if err := json.Unmarshal([]byte(body), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "status", want: "up"},
+		{path: "checks.0.name", want: "db"},
+		{path: "checks.0.status", want: "down"},
+		{path: "version.major", want: "2"},
+	}
+	for _, tt := range tests {
+		value, err := jsonPath(doc, tt.path)
+		if err != nil {
+			t.Errorf("jsonPath(%q) error = %v", tt.path, err)
+			continue
+		}
+		if got := fmt.Sprint(value); got != tt.want {
+			t.Errorf("jsonPath(%q) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+
+	for _, path := range []string{"missing", "checks.1.name", "checks.-1", "checks.first", "status.value", ""} {
+		if value, err := jsonPath(doc, path); err == nil {
+			t.Errorf("jsonPath(%q) = %v, want error", path, value)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package waitfor
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/ingridhq/comptest/logs"
+)
+
+// Output is output of the running binary which LogLine checks are bound to.
+type Output struct {
+	// Logs are lines written by the binary since it was started.
+	Logs logs.Cursor
+	// Exited is closed when the binary exits.
+	Exited <-chan struct{}
+	// Err describes why the binary exited, it can be nil.
+	Err func() error
+}
+
+type outputKey struct{}
+
+// WithOutput binds output of the binary to the context passed to checks.
+// comptest binds output of the started binary to its readiness check.
+func WithOutput(ctx context.Context, out Output) context.Context {
+	return context.WithValue(ctx, outputKey{}, out)
+}
+
+// LogLine succeeds when the binary writes a line matching regular expression, e.g. "server started".
+// It can only be used as a readiness check of a binary. It fails without retrying when the binary exits before.
+func LogLine(pattern string) logLineHealthCheck {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return logLineHealthCheck{desc: pattern, err: fmt.Errorf("invalid pattern: %w", err)}
+	}
+	return LogMatch(logs.MatchFunc(pattern, func(l logs.Line) bool {
+		return re.MatchString(l.Text)
+	}))
+}
+
+// LogMatch works like LogLine, but lines are matched with the matcher, e.g. logs.Message("ready").
+func LogMatch(m logs.Matcher) logLineHealthCheck {
+	return logLineHealthCheck{desc: m.String(), matcher: m}
+}
+
+type logLineHealthCheck struct {
+	desc    string
+	matcher logs.Matcher
+	err     error
+}
+
+func (c logLineHealthCheck) String() string {
+	return fmt.Sprintf("[LogLineCheck: %s]", c.desc)
+}
+
+func (c logLineHealthCheck) Check(ctx context.Context) error {
+	if c.err != nil {
+		return Permanent(c.err)
+	}
+
+	out, ok := ctx.Value(outputKey{}).(Output)
+	if !ok {
+		return Permanent(fmt.Errorf("check is not bound to output of a binary, use it as readiness check of the binary"))
+	}
+
+	waitCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-out.Exited:
+			cancel()
+		case <-waitCtx.Done():
+		}
+	}()
+
+	if _, err := out.Logs.WaitForLine(waitCtx, c.matcher); err == nil {
+		return nil
+	}
+
+	select {
+	case <-out.Exited:
+		// The line could be written just before the exit.
+		if len(out.Logs.Find(c.matcher)) > 0 {
+			return nil
+		}
+		if err := out.Err(); err != nil {
+			return Permanent(fmt.Errorf("binary exited before the line appeared: %w", err))
+		}
+		return Permanent(fmt.Errorf("binary exited before the line appeared"))
+	default:
+		return fmt.Errorf("line not found: %w", ctx.Err())
+	}
+}
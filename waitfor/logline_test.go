@@ -0,0 +1,101 @@
+package waitfor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ingridhq/comptest/logs"
+)
+
+// bindOutput binds output of the watcher written from now on to the context.
+// Returned exit simulates exit of the binary with the error.
+func bindOutput(ctx context.Context, w *logs.Watcher) (context.Context, func(err error)) {
+	exited := make(chan struct{})
+	var exitErr error
+	ctx = WithOutput(ctx, Output{
+		Logs:   w.Since(w.Mark()),
+		Exited: exited,
+		Err:    func() error { return exitErr },
+	})
+	return ctx, func(err error) {
+		exitErr = err
+		close(exited)
+	}
+}
+
+func TestLogLine(t *testing.T) {
+	w := logs.NewWatcher()
+	fmt.Fprintln(w, "server started by previous run")
+	ctx, _ := bindOutput(testContext(t), w)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		fmt.Fprintln(w, "server started on :8080")
+	}()
+	if err := Wait(ctx, LogLine(`server started on :\d+`), ConstantRetryPolicy(time.Millisecond)); err != nil {
+		t.Errorf("Wait() = %v", err)
+	}
+	if err := LogMatch(logs.Contains("on :8080")).Check(ctx); err != nil {
+		t.Errorf("LogMatch() = %v", err)
+	}
+}
+
+func TestLogLineSinceStart(t *testing.T) {
+	w := logs.NewWatcher()
+	fmt.Fprintln(w, "ready")
+	ctx, _ := bindOutput(testContext(t), w)
+
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := LogLine("ready").Check(ctx); err == nil || !strings.Contains(err.Error(), "line not found") {
+		t.Errorf("Check() = %v, want line written before the start to be ignored", err)
+	}
+}
+
+func TestLogLineExited(t *testing.T) {
+	w := logs.NewWatcher()
+	ctx, exit := bindOutput(testContext(t), w)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		exit(errors.New("exit status 1"))
+	}()
+	start := time.Now()
+	err := Wait(ctx, LogLine("ready"), ConstantRetryPolicy(time.Millisecond))
+	if err == nil || !strings.Contains(err.Error(), "binary exited before the line appeared: exit status 1") {
+		t.Errorf("Wait() = %v, want exit of the binary", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Wait() took %v, want to stop when the binary exits", elapsed)
+	}
+}
+
+func TestLogLineBeforeExit(t *testing.T) {
+	w := logs.NewWatcher()
+	ctx, exit := bindOutput(testContext(t), w)
+
+	fmt.Fprintln(w, "ready")
+	exit(nil)
+	if err := LogLine("ready").Check(ctx); err != nil {
+		t.Errorf("Check() = %v, want line written just before the exit to be found", err)
+	}
+	if err := LogLine("other").Check(ctx); err == nil || !isPermanent(err) {
+		t.Errorf("Check() = %v, want permanent error", err)
+	}
+}
+
+func TestLogLineErrors(t *testing.T) {
+	if err := LogLine("(").Check(testContext(t)); err == nil || !isPermanent(err) || !strings.Contains(err.Error(), "invalid pattern") {
+		t.Errorf("Check() = %v, want permanent error of invalid pattern", err)
+	}
+	if err := LogLine("ready").Check(testContext(t)); err == nil || !isPermanent(err) || !strings.Contains(err.Error(), "not bound") {
+		t.Errorf("Check() = %v, want permanent error of unbound check", err)
+	}
+	if got := LogLine("ready").String(); got != "[LogLineCheck: ready]" {
+		t.Errorf("String() = %q", got)
+	}
+}
@@ -0,0 +1,54 @@
+package waitfor
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+)
+
+// http2Preface is sent by HTTP/2 clients at the beginning of connection, followed by SETTINGS frame.
+var http2Preface = []byte("PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n")
+
+// emptySettingsFrame is HTTP/2 SETTINGS frame without parameters: length 0, type 0x4, no flags, stream 0.
+var emptySettingsFrame = []byte{0, 0, 0, 0x4, 0, 0, 0, 0, 0}
+
+// Redis succeeds when Redis server at addr answers PING.
+// Error replies, e.g. "-LOADING" while the dataset is loaded or "-NOAUTH", fail the attempt.
+func Redis(addr string) tcpHealthCheck {
+	c := TCP(addr,
+		TCPSend([]byte("*1\r\n$4\r\nPING\r\n")),
+		tcpExpectReply(`"+PONG\r\n"`, redisPong),
+	)
+	c.name = "RedisCheck"
+	return c
+}
+
+// HTTP2 succeeds when server at addr answers HTTP/2 connection preface with SETTINGS frame,
+// e.g. GRPC server. TLS is not supported.
+func HTTP2(addr string) tcpHealthCheck {
+	c := TCP(addr,
+		TCPSend(append(append([]byte{}, http2Preface...), emptySettingsFrame...)),
+		TCPExpectFunc("SETTINGS frame", func(received []byte) bool {
+			// Server starts with SETTINGS frame, type of the frame is 4th byte of its header.
+			return len(received) >= 9 && received[3] == 0x4
+		}),
+	)
+	c.name = "HTTP2Check"
+	return c
+}
+
+// PubSubEmulator succeeds when PubSub emulator at addr, e.g. PUBSUB_EMULATOR_HOST, serves HTTP requests.
+func PubSubEmulator(addr string) httpHealthCheck {
+	return HTTP("http://"+addr+"/", HTTPStatus(http.StatusOK), HTTPBodyContains("Ok"))
+}
+
+// redisPong accepts PONG reply and rejects error reply, which starts with "-".
+func redisPong(received []byte) (bool, error) {
+	if bytes.HasPrefix(received, []byte("-")) {
+		if i := bytes.Index(received, []byte("\r\n")); i >= 0 {
+			return false, fmt.Errorf("redis replied with error %q", received[1:i])
+		}
+		return false, nil
+	}
+	return bytes.Contains(received, []byte("+PONG\r\n")), nil
+}
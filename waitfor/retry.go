@@ -0,0 +1,204 @@
+package waitfor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+)
+
+// RetryPolicy describes how often and for how long a check is retried.
+type RetryPolicy struct {
+	// InitialInterval is a delay after the first failed attempt.
+	InitialInterval time.Duration
+	// Multiplier increases the delay after every failed attempt. Values <= 1 mean constant delay.
+	Multiplier float64
+	// MaxInterval caps the delay between attempts.
+	MaxInterval time.Duration
+	// MaxElapsedTime stops retrying after given time. Zero means no limit other than context deadline.
+	MaxElapsedTime time.Duration
+	// MaxAttempts stops retrying after given number of attempts. Zero means no limit.
+	MaxAttempts int
+	// AttemptTimeout limits duration of a single attempt. Zero means no limit.
+	AttemptTimeout time.Duration
+}
+
+// DefaultRetryPolicy returns exponential policy with defaults of github.com/cenkalti/backoff.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		InitialInterval: backoff.DefaultInitialInterval,
+		Multiplier:      backoff.DefaultMultiplier,
+		MaxInterval:     backoff.DefaultMaxInterval,
+		MaxElapsedTime:  backoff.DefaultMaxElapsedTime,
+	}
+}
+
+// ConstantRetryPolicy returns policy which retries check with the same interval.
+func ConstantRetryPolicy(interval time.Duration) RetryPolicy {
+	return RetryPolicy{
+		InitialInterval: interval,
+		Multiplier:      1,
+		MaxInterval:     interval,
+	}
+}
+
+// ExponentialRetryPolicy returns policy which starts with initial interval and grows it up to maxInterval.
+func ExponentialRetryPolicy(initial, maxInterval time.Duration) RetryPolicy {
+	return RetryPolicy{
+		InitialInterval: initial,
+		Multiplier:      backoff.DefaultMultiplier,
+		MaxInterval:     maxInterval,
+	}
+}
+
+func (p RetryPolicy) newBackOff() backoff.BackOff {
+	b := backoff.NewExponentialBackOff()
+	b.MaxElapsedTime = p.MaxElapsedTime
+
+	if p.InitialInterval > 0 {
+		b.InitialInterval = p.InitialInterval
+	}
+	if p.MaxInterval > 0 {
+		b.MaxInterval = p.MaxInterval
+	}
+
+	b.Multiplier = p.Multiplier
+	if p.Multiplier <= 1 {
+		b.Multiplier = 1
+		b.RandomizationFactor = 0
+	}
+
+	b.Reset()
+	return b
+}
+
+// WithRetryPolicy overrides retry policy of the suite for the check.
+// The policy applies when the check is waited for directly (e.g. passed to HealthChecks),
+// also when it's wrapped with WithName or WithTimeout.
+// It is ignored when the check is nested in All, Any or Sequence.
+func WithRetryPolicy(check Checker, policy RetryPolicy) retryHealthCheck {
+	return retryHealthCheck{check: check, policy: policy}
+}
+
+type retryHealthCheck struct {
+	check  Checker
+	policy RetryPolicy
+}
+
+func (c retryHealthCheck) String() string {
+	return fmt.Sprintf("%v", c.check)
+}
+
+func (c retryHealthCheck) Check(ctx context.Context) error {
+	return c.check.Check(ctx)
+}
+
+// RetryPolicy returns the policy the check should be retried with.
+func (c retryHealthCheck) RetryPolicy() RetryPolicy {
+	return c.policy
+}
+
+// retryPolicyOf returns retry policy set with WithRetryPolicy, looking through WithName and WithTimeout.
+func retryPolicyOf(check Checker) (RetryPolicy, bool) {
+	for {
+		switch c := check.(type) {
+		case interface{ RetryPolicy() RetryPolicy }:
+			return c.RetryPolicy(), true
+		case namedHealthCheck:
+			check = c.check
+		case timeoutHealthCheck:
+			check = c.check
+		default:
+			return RetryPolicy{}, false
+		}
+	}
+}
+
+// Wait retries the check according to the policy until it succeeds.
+// Policy set with WithRetryPolicy takes precedence over the given one.
+func Wait(ctx context.Context, check Checker, policy RetryPolicy) error {
+	return WaitNotify(ctx, check, policy, nil)
+}
+
+// WaitNotify works like Wait and additionally calls notify after every attempt.
+// Error passed to notify is nil when the attempt succeeded.
+func WaitNotify(ctx context.Context, check Checker, policy RetryPolicy, notify func(attempt int, err error)) error {
+	if p, ok := retryPolicyOf(check); ok {
+		policy = p
+	}
+
+	b := policy.newBackOff()
+	for attempt := 1; ; attempt++ {
+		err := policy.attempt(ctx, check)
+		if notify != nil {
+			notify(attempt, err)
+		}
+		if err == nil {
+			return nil
+		}
+		err = fmt.Errorf("check %v failed: %w", check, err)
+		if isPermanent(err) {
+			return err
+		}
+
+		if policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts {
+			return fmt.Errorf("%w (gave up after %d attempts)", err, attempt)
+		}
+
+		next := b.NextBackOff()
+		if next == backoff.Stop {
+			return err
+		}
+
+		t := time.NewTimer(next)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+	}
+}
+
+func (p RetryPolicy) attempt(ctx context.Context, check Checker) error {
+	if p.AttemptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.AttemptTimeout)
+		defer cancel()
+	}
+	return check.Check(ctx)
+}
+
+// Permanent wraps error returned by the check to stop retrying it, e.g. when the checked binary exited.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// isPermanent reports whether err wraps permanent error. Combined errors are not searched,
+// combinators decide themselves whether their failure is permanent.
+func isPermanent(err error) bool {
+	for err != nil {
+		if _, ok := err.(*permanentError); ok {
+			return true
+		}
+		err = errors.Unwrap(err)
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package waitfor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// failTimes returns check which fails n times and then succeeds, and counter of its attempts.
+func failTimes(n int32) (Checker, *int32) {
+	var attempts int32
+	return checkFunc(func(ctx context.Context) error {
+		if atomic.AddInt32(&attempts, 1) <= n {
+			return errors.New("not ready")
+		}
+		return nil
+	}), &attempts
+}
+
+func TestWaitRetries(t *testing.T) {
+	check, attempts := failTimes(3)
+
+	var notified []string
+	err := WaitNotify(testContext(t), check, ConstantRetryPolicy(time.Millisecond), func(attempt int, err error) {
+		status := "ok"
+		if err != nil {
+			status = err.Error()
+		}
+		notified = append(notified, status)
+	})
+	if err != nil {
+		t.Fatalf("WaitNotify() = %v", err)
+	}
+	if *attempts != 4 {
+		t.Errorf("check was run %d times, want 4", *attempts)
+	}
+	if got := strings.Join(notified, ","); got != "not ready,not ready,not ready,ok" {
+		t.Errorf("notified about %s", got)
+	}
+}
+
+func TestWaitMaxAttempts(t *testing.T) {
+	check, attempts := failTimes(10)
+	policy := ConstantRetryPolicy(time.Millisecond)
+	policy.MaxAttempts = 3
+
+	err := Wait(testContext(t), check, policy)
+	if err == nil || !strings.Contains(err.Error(), "gave up after 3 attempts") {
+		t.Errorf("Wait() = %v, want to give up", err)
+	}
+	if *attempts != 3 {
+		t.Errorf("check was run %d times, want 3", *attempts)
+	}
+}
+
+func TestWaitMaxElapsedTime(t *testing.T) {
+	check, _ := failTimes(1000)
+	policy := ConstantRetryPolicy(10 * time.Millisecond)
+	policy.MaxElapsedTime = 50 * time.Millisecond
+
+	start := time.Now()
+	if err := Wait(testContext(t), check, policy); err == nil {
+		t.Fatalf("Wait() succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Wait() took %v, want to stop after MaxElapsedTime", elapsed)
+	}
+}
+
+func TestWaitContextDone(t *testing.T) {
+	check, _ := failTimes(1000)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := Wait(ctx, check, ConstantRetryPolicy(10*time.Millisecond)); err == nil || !strings.Contains(err.Error(), "not ready") {
+		t.Errorf("Wait() = %v, want last error of the check", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Wait() took %v after context was done", elapsed)
+	}
+}
+
+func TestWaitAttemptTimeout(t *testing.T) {
+	var attempts int32
+	check := checkFunc(func(ctx context.Context) error {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			// The first attempt hangs, e.g. connection to a proxy without backend.
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	})
+	policy := ConstantRetryPolicy(time.Millisecond)
+	policy.AttemptTimeout = 20 * time.Millisecond
+
+	if err := Wait(testContext(t), check, policy); err != nil {
+		t.Errorf("Wait() = %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("check was run %d times, want 2", attempts)
+	}
+}
+
+func TestWaitPermanent(t *testing.T) {
+	var attempts int32
+	check := checkFunc(func(ctx context.Context) error {
+		atomic.AddInt32(&attempts, 1)
+		return Permanent(errors.New("binary exited"))
+	})
+
+	err := Wait(testContext(t), check, ConstantRetryPolicy(time.Millisecond))
+	if err == nil || !strings.Contains(err.Error(), "binary exited") {
+		t.Errorf("Wait() = %v, want permanent error", err)
+	}
+	if attempts != 1 {
+		t.Errorf("permanent error was retried %d times", attempts-1)
+	}
+	if Permanent(nil) != nil {
+		t.Errorf("Permanent(nil) is not nil")
+	}
+}
+
+func TestWithRetryPolicy(t *testing.T) {
+	policy := ConstantRetryPolicy(time.Millisecond)
+	policy.MaxAttempts = 2
+
+	// The policy of the check takes precedence over the given one, also through wrappers.
+	for _, wrap := range []func(c Checker) Checker{
+		func(c Checker) Checker { return c },
+		func(c Checker) Checker { return WithName(c, "named") },
+		func(c Checker) Checker { return WithTimeout(WithName(c, "named"), time.Second) },
+	} {
+		check, attempts := failTimes(10)
+		err := Wait(testContext(t), wrap(WithRetryPolicy(check, policy)), ConstantRetryPolicy(time.Millisecond))
+		if err == nil || !strings.Contains(err.Error(), "gave up after 2 attempts") {
+			t.Errorf("Wait() = %v, want policy of the check", err)
+		}
+		if *attempts != 2 {
+			t.Errorf("check was run %d times, want 2", *attempts)
+		}
+	}
+
+	// Policy of nested check is ignored.
+	check, attempts := failTimes(10)
+	outer := ConstantRetryPolicy(time.Millisecond)
+	outer.MaxAttempts = 4
+	if err := Wait(testContext(t), All(WithRetryPolicy(check, policy)), outer); err == nil {
+		t.Errorf("Wait() succeeded")
+	}
+	if *attempts != 4 {
+		t.Errorf("nested check was run %d times, want 4", *attempts)
+	}
+}
+
+func TestRetryPolicyIntervals(t *testing.T) {
+	b := ExponentialRetryPolicy(10*time.Millisecond, 40*time.Millisecond).newBackOff()
+	var max time.Duration
+	for i := 0; i < 10; i++ {
+		if next := b.NextBackOff(); next > max {
+			max = next
+		}
+	}
+	// Intervals are randomized by up to 50%.
+	if max > 60*time.Millisecond {
+		t.Errorf("interval grew to %v, want at most 40ms +50%%", max)
+	}
+
+	b = ConstantRetryPolicy(15 * time.Millisecond).newBackOff()
+	for i := 0; i < 5; i++ {
+		if next := b.NextBackOff(); next != 15*time.Millisecond {
+			t.Fatalf("interval %d = %v, want 15ms", i, next)
+		}
+	}
+}
@@ -1,37 +1,159 @@
 package waitfor
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"net"
 	"strings"
+	"time"
+
+	"github.com/ingridhq/comptest/ports"
 )
 
-func TCP(addr string) tcpHealthCheck {
-	return tcpHealthCheck{
+// maxReceivedSize limits data read by TCP check while waiting for expected response.
+const maxReceivedSize = 64 << 10
+
+// tcpReadTimeout limits waiting for expected response, so the attempt is retried when the server,
+// or a proxy in front of it, accepts the connection but doesn't answer.
+const tcpReadTimeout = 2 * time.Second
+
+// TCP succeeds when connection to addr is established and all Send/Expect steps succeed.
+func TCP(addr string, opts ...TCPOption) tcpHealthCheck {
+	c := tcpHealthCheck{
+		name: "TCPCheck",
 		addr: addr,
 	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// TCPOption configures TCP check.
+type TCPOption func(c *tcpHealthCheck)
+
+// TCPSend sends data to the connection. Steps are run in order of options.
+func TCPSend(data []byte) TCPOption {
+	return func(c *tcpHealthCheck) {
+		c.steps = append(c.steps, tcpStep{send: data})
+	}
+}
+
+// TCPExpect reads from the connection until received data contains expected bytes.
+// The attempt fails when they don't arrive within 2 seconds.
+func TCPExpect(expected []byte) TCPOption {
+	return TCPExpectFunc(fmt.Sprintf("%q", expected), func(received []byte) bool {
+		return bytes.Contains(received, expected)
+	})
+}
+
+// TCPExpectFunc reads from the connection until fn accepts received data. Description is used in errors.
+// The attempt fails when fn doesn't accept the data within 2 seconds.
+func TCPExpectFunc(desc string, fn func(received []byte) bool) TCPOption {
+	return tcpExpectReply(desc, func(received []byte) (bool, error) {
+		return fn(received), nil
+	})
+}
+
+// tcpExpectReply works like TCPExpectFunc, but fn can also reject the response with error.
+func tcpExpectReply(desc string, fn func(received []byte) (bool, error)) TCPOption {
+	return func(c *tcpHealthCheck) {
+		c.steps = append(c.steps, tcpStep{desc: desc, expect: fn})
+	}
+}
+
+type tcpStep struct {
+	send   []byte
+	desc   string
+	expect func(received []byte) (bool, error)
 }
 
 type tcpHealthCheck struct {
-	addr string
+	name  string
+	addr  string
+	steps []tcpStep
 }
 
 func (c tcpHealthCheck) String() string {
-	return fmt.Sprintf("[TCPCheck: %s]", c.addr)
+	return fmt.Sprintf("[%s: %s]", c.name, c.addr)
 }
 
 func (c tcpHealthCheck) Check(ctx context.Context) error {
+	addr, err := ports.ResolveReserved(cleanupAddress(c.addr))
+	if err != nil {
+		return Permanent(err)
+	}
+
 	d := net.Dialer{}
-	conn, err := d.DialContext(ctx, "tcp", cleanupAddress(c.addr))
+	conn, err := d.DialContext(ctx, "tcp", addr)
 
 	if err != nil {
 		return fmt.Errorf("failed to connect: %w", err)
 	}
-	conn.Close()
+	defer conn.Close()
+
+	if len(c.steps) == 0 {
+		return nil
+	}
+
+	// Unblock reads and writes when the context is done.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	for _, step := range c.steps {
+		if step.expect == nil {
+			if _, err := conn.Write(step.send); err != nil {
+				return fmt.Errorf("failed to send data: %w", err)
+			}
+			continue
+		}
+		if err := expect(conn, step); err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("%v: %w", err, ctx.Err())
+			}
+			return err
+		}
+	}
 	return nil
 }
 
+func expect(conn net.Conn, step tcpStep) error {
+	if err := conn.SetReadDeadline(time.Now().Add(tcpReadTimeout)); err != nil {
+		return fmt.Errorf("failed to set read deadline: %w", err)
+	}
+
+	var (
+		received []byte
+		readErr  error
+	)
+	buf := make([]byte, 4096)
+	for {
+		done, err := step.expect(received)
+		switch {
+		case err != nil:
+			return fmt.Errorf("expected %s: %w", step.desc, err)
+		case done:
+			return nil
+		case readErr != nil:
+			return fmt.Errorf("expected %s, received %q: %w", step.desc, received, readErr)
+		case len(received) > maxReceivedSize:
+			return fmt.Errorf("expected %s, received too much data", step.desc)
+		}
+
+		var n int
+		n, readErr = conn.Read(buf)
+		received = append(received, buf[:n]...)
+	}
+}
+
 func cleanupAddress(addr string) string {
 	return strings.Split(addr, "?")[0]
 }
@@ -0,0 +1,150 @@
+package waitfor
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveTCP starts TCP server which calls handle for every connection and keeps it open
+// until the test ends.
+func serveTCP(t *testing.T, handle func(conn net.Conn)) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		close(done)
+		l.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+				<-done
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+// reply reads a request and writes the response.
+func reply(response string) func(conn net.Conn) {
+	return func(conn net.Conn) {
+		buf := make([]byte, 1024)
+		if _, err := conn.Read(buf); err != nil {
+			return
+		}
+		// The check reports missing response, the error isn't needed here.
+		_, _ = conn.Write([]byte(response))
+	}
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestTCP(t *testing.T) {
+	addr := serveTCP(t, func(conn net.Conn) {})
+	if err := TCP(addr).Check(testContext(t)); err != nil {
+		t.Errorf("TCP() = %v", err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := l.Addr().String()
+	l.Close()
+	if err := TCP(closed).Check(testContext(t)); err == nil {
+		t.Errorf("TCP() of closed port succeeded")
+	}
+}
+
+func TestTCPSendExpect(t *testing.T) {
+	addr := serveTCP(t, reply("hello, "))
+	check := TCP(addr, TCPSend([]byte("hi")), TCPExpect([]byte("hello")))
+	if err := check.Check(testContext(t)); err != nil {
+		t.Errorf("Check() = %v", err)
+	}
+
+	closing := serveTCP(t, func(conn net.Conn) {
+		reply("bye")(conn)
+		conn.Close()
+	})
+	check = TCP(closing, TCPSend([]byte("hi")), TCPExpect([]byte("hello")))
+	if err := check.Check(testContext(t)); err == nil || !strings.Contains(err.Error(), `received "bye"`) {
+		t.Errorf("Check() = %v, want error with received data", err)
+	}
+}
+
+func TestTCPExpectTimeout(t *testing.T) {
+	// Server, or docker-proxy without backend, accepts the connection but never answers.
+	addr := serveTCP(t, func(conn net.Conn) {})
+
+	start := time.Now()
+	err := TCP(addr, TCPExpect([]byte("hello"))).Check(testContext(t))
+	if err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("Check() = %v, want timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > tcpReadTimeout+time.Second {
+		t.Errorf("Check() took %v, want about %v", elapsed, tcpReadTimeout)
+	}
+}
+
+func TestRedis(t *testing.T) {
+	if err := Redis(serveTCP(t, reply("+PONG\r\n"))).Check(testContext(t)); err != nil {
+		t.Errorf("Redis() = %v", err)
+	}
+
+	for _, resp := range []string{"-LOADING Redis is loading the dataset in memory\r\n", "-NOAUTH Authentication required.\r\n"} {
+		start := time.Now()
+		err := Redis(serveTCP(t, reply(resp))).Check(testContext(t))
+		if err == nil || !strings.Contains(err.Error(), strings.Fields(resp)[0][1:]) {
+			t.Errorf("Redis() with reply %q = %v, want error reply", resp, err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Redis() with reply %q took %v, want to fail at once", resp, elapsed)
+		}
+	}
+
+	check := Redis("127.0.0.1:6379")
+	if got := check.String(); got != "[RedisCheck: 127.0.0.1:6379]" {
+		t.Errorf("String() = %s", got)
+	}
+}
+
+func TestHTTP2(t *testing.T) {
+	settings := string([]byte{0, 0, 6, 0x4, 0, 0, 0, 0, 0, 0, 3, 0, 0, 0, 100})
+	if err := HTTP2(serveTCP(t, reply(settings))).Check(testContext(t)); err != nil {
+		t.Errorf("HTTP2() = %v", err)
+	}
+
+	// HTTP/1 server answers the preface with an error and closes the connection.
+	http1 := serveTCP(t, func(conn net.Conn) {
+		reply("HTTP/1.1 400 Bad Request\r\n\r\n")(conn)
+		conn.Close()
+	})
+	if err := HTTP2(http1).Check(testContext(t)); err == nil || !strings.Contains(err.Error(), "SETTINGS frame") {
+		t.Errorf("HTTP2() of HTTP/1 server = %v, want error", err)
+	}
+}
+
+func TestTCPUnknownPort(t *testing.T) {
+	err := TCP("localhost:{port:waitfor-tcp-unknown}").Check(testContext(t))
+	if !isPermanent(err) {
+		t.Errorf("Check() = %v, want permanent error", err)
+	}
+}
@@ -0,0 +1,62 @@
+package waitfor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/ingridhq/comptest/ports"
+)
+
+// udpReplyTimeout is how long UDP check waits for rejection of the datagram.
+const udpReplyTimeout = 200 * time.Millisecond
+
+// UDP succeeds when empty datagram sent to addr is not rejected, e.g. by statsd.
+// UDP is connectionless, so the check only detects closed ports reported with ICMP "port unreachable".
+func UDP(addr string) udpHealthCheck {
+	return udpHealthCheck{addr: addr}
+}
+
+type udpHealthCheck struct {
+	addr string
+}
+
+func (c udpHealthCheck) String() string {
+	return fmt.Sprintf("[UDPCheck: %s]", c.addr)
+}
+
+func (c udpHealthCheck) Check(ctx context.Context) error {
+	addr, err := ports.ResolveReserved(c.addr)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	d := net.Dialer{}
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write(nil); err != nil {
+		return fmt.Errorf("failed to send datagram: %w", err)
+	}
+
+	deadline := time.Now().Add(udpReplyTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return fmt.Errorf("failed to set read deadline: %w", err)
+	}
+
+	// Closed port is reported as error of the read, any reply or silence means the port is open.
+	_, err = conn.Read(make([]byte, 1))
+	var netErr net.Error
+	if err != nil && !(errors.As(err, &netErr) && netErr.Timeout()) {
+		return fmt.Errorf("datagram rejected: %w", err)
+	}
+	return nil
+}
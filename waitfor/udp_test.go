@@ -0,0 +1,35 @@
+package waitfor
+
+import (
+	"net"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := conn.LocalAddr().String()
+	ctx := testContext(t)
+
+	if err := UDP(addr).Check(ctx); err != nil {
+		t.Errorf("Check() = %v", err)
+	}
+
+	conn.Close()
+	if runtime.GOOS != "linux" {
+		t.Skip("rejected datagrams are reported to the sender only on linux")
+	}
+	if err := UDP(addr).Check(ctx); err == nil || !strings.Contains(err.Error(), "datagram rejected") {
+		t.Errorf("Check() = %v, want rejected datagram", err)
+	}
+}
+
+func TestUDPUnknownPort(t *testing.T) {
+	if err := UDP("localhost:{port:waitfor-udp-unknown}").Check(testContext(t)); err == nil || !isPermanent(err) {
+		t.Errorf("Check() = %v, want permanent error of unknown port", err)
+	}
+}